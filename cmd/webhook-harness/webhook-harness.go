@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/telegram"
+	"gopkg.in/telebot.v3"
+)
+
+// webhook-harness posts a synthetic private message to the bot running in webhook mode,
+// the bot replies to the user via Telegram as usual.
+var (
+	flagURL    = flag.String("url", "http://localhost:8443/", "webhook listener URL")
+	flagSecret = flag.String("secret", "", "webhook secret token")
+	flagUser   = flag.Int64("user", 0, "ID of the user the message is sent from")
+	flagText   = flag.String("text", "https://www.youtube.com/watch?v=dQw4w9WgXcQ", "message text")
+)
+
+func main() {
+	flag.Parse()
+
+	if *flagUser == 0 {
+		log.Logger.Fatal("-user is required, the bot replies to this user")
+	}
+
+	now := time.Now()
+	user := &telebot.User{ID: *flagUser, FirstName: "harness"}
+	update := telebot.Update{
+		ID: int(now.Unix()),
+		Message: &telebot.Message{
+			ID:       int(now.Unix()),
+			Unixtime: now.Unix(),
+			Sender:   user,
+			Chat:     &telebot.Chat{ID: user.ID, Type: telebot.ChatPrivate, FirstName: user.FirstName},
+			Text:     *flagText,
+		},
+	}
+
+	body, err := json.Marshal(update)
+	if err != nil {
+		log.Logger.Fatalw("failed to encode update", "error", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, *flagURL, bytes.NewReader(body))
+	if err != nil {
+		log.Logger.Fatalw("failed to create request", "error", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(*flagSecret) > 0 {
+		req.Header.Set(telegram.SecretTokenHeader, *flagSecret)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Logger.Fatalw("failed to post update", "error", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	fmt.Println(resp.Status, string(respBody))
+}
@@ -2,6 +2,8 @@ package app
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/far4599/telegram-bot-youtube-download/internal/app/bot"
 	"github.com/far4599/telegram-bot-youtube-download/internal/config"
@@ -21,20 +23,56 @@ func NewApp(conf *config.Config) *App {
 }
 
 func (app *App) Run(ctx context.Context) error {
-	inMemRepo, err := repository.NewInMemRepository()
+	db, err := repository.NewBoltDB(app.conf.Storage.Path)
 	if err != nil {
 		return err
 	}
+	defer db.Close()
 
-	vs, err := service.NewVideoService(2, inMemRepo)
+	errGroup, errCtx := errgroup.WithContext(ctx)
+
+	var optionRepo repository.Repository
+	switch app.conf.Storage.OptionsBackend {
+	case "memory":
+		optionRepo, err = repository.NewInMemRepository(app.conf.Storage.OptionsTTL)
+		if err != nil {
+			return err
+		}
+	case "bolt":
+		boltRepo := repository.NewBoltRepository(db, app.conf.Storage.OptionsTTL)
+		errGroup.Go(func() error {
+			return boltRepo.RunCleanup(errCtx, time.Hour)
+		})
+		optionRepo = boltRepo
+	default:
+		return fmt.Errorf("unknown video options storage backend '%s'", app.conf.Storage.OptionsBackend)
+	}
+
+	dl := service.NewYtDlpDownloader(app.conf.Downloader.Binary, app.conf.Downloader.MaxRetry)
+
+	searchRepo, err := repository.NewSearchRepository()
 	if err != nil {
 		return err
 	}
 
-	errGroup, errCtx := errgroup.WithContext(ctx)
+	vs, err := service.NewVideoService(app.conf, dl, optionRepo, repository.NewMediaCacheRepository(db), searchRepo)
+	if err != nil {
+		return err
+	}
+
+	if len(app.conf.Upload.LinkDir) > 0 {
+		errGroup.Go(func() error {
+			return vs.RunLinkCleanup(errCtx, time.Hour)
+		})
+	}
+
+	qs, err := service.NewQueueService(app.conf.Queue.Workers, repository.NewJobRepository(db))
+	if err != nil {
+		return err
+	}
 
 	// errGroup.Go(func() error {
-	// 	s, _ := service.NewVideoService(2, inMemRepo)
+	// 	s, _ := service.NewVideoService(app.conf, dl, optionRepo, nil)
 	//
 	// 	options, err := s.GetVideoOptions(errCtx, "https://youtube.com/shorts/baUkeYKZa9Y")
 	// 	if err != nil {
@@ -46,8 +84,22 @@ func (app *App) Run(ctx context.Context) error {
 	// 	return nil
 	// })
 
+	as := service.NewAccessService(app.conf, repository.NewAccessRepository(db))
+	ls := service.NewLimitService(app.conf, repository.NewUsageRepository(db), qs, as)
+	gs := service.NewGroupService(app.conf, repository.NewGroupRepository(db))
+	ss := service.NewSettingsService(repository.NewSettingsRepository(db))
+	st := service.NewStatsService(repository.NewStatsRepository(db))
+
+	b := bot.NewApp(app.conf, vs, qs, as, ls, gs, ss, st)
+
+	if len(app.conf.HTTP.Listen) > 0 {
+		errGroup.Go(func() error {
+			return serveHTTP(errCtx, app.conf.HTTP.Listen, b)
+		})
+	}
+
 	errGroup.Go(func() error {
-		return bot.NewApp(app.conf, vs).Run(errCtx)
+		return b.Run(errCtx)
 	})
 
 	return errGroup.Wait()
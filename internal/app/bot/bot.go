@@ -2,27 +2,46 @@ package bot
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/far4599/telegram-bot-youtube-download/internal/config"
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
 	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/telegram"
 	"github.com/far4599/telegram-bot-youtube-download/internal/service"
 	"github.com/gotd/td/session"
 	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
 	"gopkg.in/telebot.v3"
 )
 
+var (
+	ErrNotStarted = fmt.Errorf("not started yet")
+	ErrNotPolling = fmt.Errorf("bot does not receive updates")
+)
+
 type Bot struct {
 	conf *config.Config
 
+	vs  *service.VideoService
+	qs  *service.QueueService
 	tmh *service.TelegramMessageHandler
+
+	// clients are set once the bot is started
+	mu        sync.Mutex
+	userbot   *telegram.UserBotClient
+	botClient *telegram.BotClient
 }
 
-func NewApp(conf *config.Config, vs *service.VideoService) *Bot {
+func NewApp(conf *config.Config, vs *service.VideoService, qs *service.QueueService, as *service.AccessService, ls *service.LimitService, gs *service.GroupService, ss *service.SettingsService, st *service.StatsService) *Bot {
 	return &Bot{
 		conf: conf,
-		tmh:  service.NewMessageHandler(conf, vs),
+		vs:   vs,
+		qs:   qs,
+		tmh:  service.NewMessageHandler(conf, vs, qs, as, ls, gs, ss, st),
 	}
 }
 
@@ -37,7 +56,7 @@ func (b *Bot) Run(ctx context.Context) error {
 func (b *Bot) run(ctx context.Context) error {
 	userbot := telegram.NewUserBotClient(ctx, b.conf)
 
-	bot, err := telegram.NewBotClient(b.conf.Telegram.Bot.Token)
+	bot, err := telegram.NewBotClient(b.conf)
 	if err != nil {
 		return err
 	}
@@ -47,9 +66,74 @@ func (b *Bot) run(ctx context.Context) error {
 		return err
 	}
 
+	b.mu.Lock()
+	b.userbot, b.botClient = userbot, bot
+	b.mu.Unlock()
+
+	// caches the version for readiness probes
+	if version, errV := b.vs.DownloaderVersion(ctx); errV != nil {
+		log.Logger.Warnw("downloader is not usable", "error", errV)
+	} else {
+		log.Logger.Infow("downloader found", "version", version)
+	}
+
+	errGroup, errCtx := errgroup.WithContext(ctx)
+
+	errGroup.Go(func() error {
+		// jobs resumed after restart would fail before the userbot is authorized
+		if err := userbot.WaitReady(errCtx); err != nil {
+			if errCtx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "userbot is not ready")
+		}
+
+		return b.qs.Run(errCtx, b.tmh.ProcessJob(bot.Bot(), userbot))
+	})
+
+	errGroup.Go(func() error {
+		select {
+		case <-errCtx.Done():
+			return nil
+		case err := <-bot.Failed():
+			return err
+		}
+	})
+
+	errGroup.Go(func() error {
+		<-errCtx.Done()
+		bot.Bot().Stop()
+		return nil
+	})
+
 	bot.Bot().Start()
 
-	return nil
+	return errGroup.Wait()
+}
+
+// Ready checks the userbot is authorized, the bot receives updates and the downloader is usable.
+func (b *Bot) Ready(ctx context.Context) []models.HealthCheck {
+	b.mu.Lock()
+	userbot, botClient := b.userbot, b.botClient
+	b.mu.Unlock()
+
+	userbotCheck := models.HealthCheck{Name: "userbot", Err: ErrNotStarted}
+	pollerCheck := models.HealthCheck{Name: "poller", Err: ErrNotStarted}
+
+	if userbot != nil {
+		userbotCheck.Err = userbot.Status()
+	}
+	if botClient != nil {
+		pollerCheck.Err = nil
+		if !botClient.Polling() {
+			pollerCheck.Err = ErrNotPolling
+		}
+	}
+
+	downloaderCheck := models.HealthCheck{Name: "downloader"}
+	downloaderCheck.Info, downloaderCheck.Err = b.vs.DownloaderVersion(ctx)
+
+	return []models.HealthCheck{userbotCheck, pollerCheck, downloaderCheck}
 }
 
 // func (b *Bot) run(ctx context.Context) error {
@@ -107,8 +191,35 @@ func (b *Bot) setMessageHandlers(botClient *telegram.BotClient, userbotClient *t
 	// dispatcher.OnNewMessage(b.tmh.OnNewMessage(api))
 	bot := botClient.Bot()
 
+	bot.Use(b.tmh.AccessMiddleware())
+
+	bot.Handle("/allow", b.tmh.OnAllow(), b.tmh.AdminMiddleware())
+	bot.Handle("/deny", b.tmh.OnDeny(), b.tmh.AdminMiddleware())
+	bot.Handle("/stats", b.tmh.OnStats(), b.tmh.AdminMiddleware())
+	bot.Handle("/jobs", b.tmh.OnJobs(), b.tmh.AdminMiddleware())
+	bot.Handle("/kill", b.tmh.OnKill(), b.tmh.AdminMiddleware())
+	bot.Handle("/broadcast", b.tmh.OnBroadcast(), b.tmh.AdminMiddleware())
+	bot.Handle("/ban", b.tmh.OnBan(), b.tmh.AdminMiddleware())
 	bot.Handle("/start", b.tmh.OnStart())
-	bot.Handle(telebot.OnText, b.tmh.OnNewMessage())
+	bot.Handle("/queue", b.tmh.OnQueue())
+	bot.Handle("/cancel", b.tmh.OnCancel())
+	bot.Handle("/group", b.tmh.OnGroupSettings())
+	bot.Handle("/settings", b.tmh.OnSettings())
+	bot.Handle(telebot.OnText, b.tmh.OnNewMessage(userbotClient))
+	// links may be sent as captions, e.g. of forwarded posts
+	for _, endpoint := range []string{telebot.OnPhoto, telebot.OnVideo, telebot.OnAnimation, telebot.OnDocument, telebot.OnAudio} {
+		bot.Handle(endpoint, b.tmh.OnNewMessage(userbotClient))
+	}
+	bot.Handle(&service.CancelJobButton, b.tmh.OnCancelJob())
+	bot.Handle(&service.TrimButton, b.tmh.OnTrim())
+	bot.Handle(&service.ChaptersButton, b.tmh.OnChapters())
+	bot.Handle(&service.ChapterButton, b.tmh.OnChapter())
+	bot.Handle(&service.InlineStatusButton, b.tmh.OnInlineStatus())
+	bot.Handle(&service.SearchPageButton, b.tmh.OnSearchPage())
+	bot.Handle(&service.SearchResultButton, b.tmh.OnSearchResult())
+	bot.Handle(&service.SettingsButton, b.tmh.OnSettingsButton())
+	bot.Handle(telebot.OnQuery, b.tmh.OnQuery())
+	bot.Handle(telebot.OnInlineResult, b.tmh.OnInlineResult(userbotClient))
 	bot.Handle(telebot.OnCallback, b.tmh.OnCallback(userbotClient))
 
 	return nil
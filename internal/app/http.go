@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/app/bot"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/metrics"
+	"github.com/pkg/errors"
+)
+
+const readyTimeout = 5 * time.Second
+
+// serveHTTP serves metrics and health checks on the address until ctx is done.
+func serveHTTP(ctx context.Context, addr string, b *bot.Bot) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		checkCtx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+		defer cancel()
+
+		var sb strings.Builder
+		status := http.StatusOK
+		for _, check := range b.Ready(checkCtx) {
+			switch {
+			case check.Err != nil:
+				status = http.StatusServiceUnavailable
+				sb.WriteString(fmt.Sprintf("%s: %s\n", check.Name, check.Err))
+			case len(check.Info) > 0:
+				sb.WriteString(fmt.Sprintf("%s: ok, %s\n", check.Name, check.Info))
+			default:
+				sb.WriteString(fmt.Sprintf("%s: ok\n", check.Name))
+			}
+		}
+
+		w.WriteHeader(status)
+		fmt.Fprint(w, sb.String())
+	})
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	log.Logger.Infow("http server started", "addr", addr)
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return errors.Wrapf(err, "failed to serve http on '%s'", addr)
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"context"
 	"os"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sethvargo/go-envconfig"
@@ -13,6 +14,15 @@ type Config struct {
 	Telegram struct {
 		Bot struct {
 			Token string `mapstructure:"token" env:"TELEGRAM_BOT_TOKEN"`
+			// Mode is how updates are received: polling or webhook
+			Mode    string `mapstructure:"mode" env:"TELEGRAM_BOT_MODE"`
+			Webhook struct {
+				Listen      string `mapstructure:"listen" env:"TELEGRAM_BOT_WEBHOOK_LISTEN"`
+				PublicURL   string `mapstructure:"public_url" env:"TELEGRAM_BOT_WEBHOOK_PUBLIC_URL"`
+				SecretToken string `mapstructure:"secret_token" env:"TELEGRAM_BOT_WEBHOOK_SECRET_TOKEN"`
+				TLSCert     string `mapstructure:"tls_cert" env:"TELEGRAM_BOT_WEBHOOK_TLS_CERT"`
+				TLSKey      string `mapstructure:"tls_key" env:"TELEGRAM_BOT_WEBHOOK_TLS_KEY"`
+			} `mapstructure:"webhook"`
 		} `mapstructure:"bot"`
 		App struct {
 			ID         int    `mapstructure:"id" env:"TELEGRAM_APP_ID"`
@@ -20,6 +30,52 @@ type Config struct {
 			SessionDir string `mapstructure:"session_dir" env:"TELEGRAM_APP_SESSION_DIR"`
 		} `mapstructure:"app"`
 	} `mapstructure:"telegram"`
+	Downloader struct {
+		Binary   string `mapstructure:"binary" env:"DOWNLOADER_BINARY"`
+		MaxRetry uint   `mapstructure:"max_retry" env:"DOWNLOADER_MAX_RETRY"`
+	} `mapstructure:"downloader"`
+	Storage struct {
+		Path           string        `mapstructure:"path" env:"STORAGE_PATH"`
+		OptionsBackend string        `mapstructure:"options_backend" env:"STORAGE_OPTIONS_BACKEND"`
+		OptionsTTL     time.Duration `mapstructure:"options_ttl" env:"STORAGE_OPTIONS_TTL"`
+	} `mapstructure:"storage"`
+	Upload struct {
+		MaxSize  uint64        `mapstructure:"max_size" env:"UPLOAD_MAX_SIZE"`
+		Oversize string        `mapstructure:"oversize" env:"UPLOAD_OVERSIZE"`
+		LinkDir  string        `mapstructure:"link_dir" env:"UPLOAD_LINK_DIR"`
+		LinkURL  string        `mapstructure:"link_url" env:"UPLOAD_LINK_URL"`
+		LinkTTL  time.Duration `mapstructure:"link_ttl" env:"UPLOAD_LINK_TTL"`
+	} `mapstructure:"upload"`
+	Playlist struct {
+		MaxEntries int `mapstructure:"max_entries" env:"PLAYLIST_MAX_ENTRIES"`
+	} `mapstructure:"playlist"`
+	Access struct {
+		AllowedUsers     []int64  `mapstructure:"allowed_users" env:"ACCESS_ALLOWED_USERS"`
+		AllowedUsernames []string `mapstructure:"allowed_usernames" env:"ACCESS_ALLOWED_USERNAMES"`
+		AllowedChats     []int64  `mapstructure:"allowed_chats" env:"ACCESS_ALLOWED_CHATS"`
+		Admins           []int64  `mapstructure:"admins" env:"ACCESS_ADMINS"`
+	} `mapstructure:"access"`
+	Limits struct {
+		ConcurrentJobs   int    `mapstructure:"concurrent_jobs" env:"LIMITS_CONCURRENT_JOBS"`
+		DownloadsPerHour int    `mapstructure:"downloads_per_hour" env:"LIMITS_DOWNLOADS_PER_HOUR"`
+		BytesPerDay      uint64 `mapstructure:"bytes_per_day" env:"LIMITS_BYTES_PER_DAY"`
+		LinksPerMessage  int    `mapstructure:"links_per_message" env:"LIMITS_LINKS_PER_MESSAGE"`
+	} `mapstructure:"limits"`
+	Queue struct {
+		Workers int `mapstructure:"workers" env:"QUEUE_WORKERS"`
+	} `mapstructure:"queue"`
+	Search struct {
+		Provider string `mapstructure:"provider" env:"SEARCH_PROVIDER"`
+		Results  int    `mapstructure:"results" env:"SEARCH_RESULTS"`
+		PageSize int    `mapstructure:"page_size" env:"SEARCH_PAGE_SIZE"`
+	} `mapstructure:"search"`
+	Group struct {
+		Mode    string `mapstructure:"mode" env:"GROUP_MODE"`
+		Quality string `mapstructure:"quality" env:"GROUP_QUALITY"`
+	} `mapstructure:"group"`
+	HTTP struct {
+		Listen string `mapstructure:"listen" env:"HTTP_LISTEN"`
+	} `mapstructure:"http"`
 }
 
 func NewConfig(ctx context.Context, configPath string) (*Config, error) {
@@ -28,7 +84,8 @@ func NewConfig(ctx context.Context, configPath string) (*Config, error) {
 		if err := envconfig.Process(ctx, &conf); err != nil {
 			return nil, errors.Wrap(err, "failed to process config environment variables")
 		}
-		return &conf, nil
+		conf.setDefaults()
+		return &conf, conf.validate()
 	}
 
 	f, err := os.Open(configPath)
@@ -44,6 +101,91 @@ func NewConfig(ctx context.Context, configPath string) (*Config, error) {
 	if err := v.Unmarshal(&conf); err != nil {
 		return nil, errors.Wrap(err, "failed to decode config yaml file")
 	}
+	conf.setDefaults()
 
-	return &conf, nil
+	return &conf, conf.validate()
+}
+
+// validate checks that the chosen modes have the settings they need.
+func (c *Config) validate() error {
+	upload := c.Upload
+	switch upload.Oversize {
+	case "split", "link":
+	default:
+		return errors.Errorf("unknown upload oversize mode '%s', expected split or link", upload.Oversize)
+	}
+
+	if upload.Oversize == "link" && (len(upload.LinkDir) == 0 || len(upload.LinkURL) == 0) {
+		return errors.New("upload link_dir and link_url are required by link oversize mode")
+	}
+
+	if (len(upload.LinkDir) == 0) != (len(upload.LinkURL) == 0) {
+		return errors.New("upload link_dir and link_url must be set together")
+	}
+
+	if len(upload.LinkDir) > 0 {
+		info, err := os.Stat(upload.LinkDir)
+		if err != nil {
+			return errors.Wrapf(err, "upload link dir '%s' is not available", upload.LinkDir)
+		}
+		if !info.IsDir() {
+			return errors.Errorf("upload link dir '%s' is not a directory", upload.LinkDir)
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) setDefaults() {
+	if len(c.Telegram.Bot.Mode) == 0 {
+		c.Telegram.Bot.Mode = "polling"
+	}
+	if len(c.Downloader.Binary) == 0 {
+		c.Downloader.Binary = "yt-dlp"
+	}
+	if c.Downloader.MaxRetry == 0 {
+		c.Downloader.MaxRetry = 2
+	}
+	if len(c.Storage.Path) == 0 {
+		c.Storage.Path = "./data/bot.db"
+	}
+	if len(c.Storage.OptionsBackend) == 0 {
+		c.Storage.OptionsBackend = "bolt"
+	}
+	if c.Storage.OptionsTTL == 0 {
+		c.Storage.OptionsTTL = 7 * 24 * time.Hour
+	}
+	if c.Upload.MaxSize == 0 {
+		c.Upload.MaxSize = 2000 * 1024 * 1024
+	}
+	if len(c.Upload.Oversize) == 0 {
+		c.Upload.Oversize = "split"
+	}
+	if c.Upload.LinkTTL == 0 {
+		c.Upload.LinkTTL = 24 * time.Hour
+	}
+	if c.Playlist.MaxEntries == 0 {
+		c.Playlist.MaxEntries = 50
+	}
+	if c.Limits.LinksPerMessage == 0 {
+		c.Limits.LinksPerMessage = 5
+	}
+	if c.Queue.Workers == 0 {
+		c.Queue.Workers = 2
+	}
+	if len(c.Search.Provider) == 0 {
+		c.Search.Provider = "ytsearch"
+	}
+	if c.Search.Results == 0 {
+		c.Search.Results = 20
+	}
+	if c.Search.PageSize == 0 {
+		c.Search.PageSize = 5
+	}
+	if len(c.Group.Mode) == 0 {
+		c.Group.Mode = "menu"
+	}
+	if len(c.Group.Quality) == 0 {
+		c.Group.Quality = "p720"
+	}
 }
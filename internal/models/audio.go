@@ -0,0 +1,28 @@
+package models
+
+type AudioCodec struct {
+	Name    string // yt-dlp --audio-format value
+	Ext     string
+	MIME    string
+	Label   string
+	Quality string // yt-dlp --audio-quality value
+}
+
+var (
+	AudioCodecMP3  = AudioCodec{Name: "mp3", Ext: "mp3", MIME: "audio/mpeg", Label: "mp3 320k", Quality: "320K"}
+	AudioCodecOpus = AudioCodec{Name: "opus", Ext: "opus", MIME: "audio/ogg", Label: "opus", Quality: "0"}
+	AudioCodecM4A  = AudioCodec{Name: "m4a", Ext: "m4a", MIME: "audio/mp4", Label: "m4a", Quality: "0"}
+
+	AudioCodecs = []AudioCodec{AudioCodecMP3, AudioCodecOpus, AudioCodecM4A}
+)
+
+// AudioCodecByName returns the codec by its name, mp3 is returned for unknown names.
+func AudioCodecByName(name string) AudioCodec {
+	for _, codec := range AudioCodecs {
+		if codec.Name == name {
+			return codec
+		}
+	}
+
+	return AudioCodecMP3
+}
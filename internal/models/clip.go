@@ -0,0 +1,27 @@
+package models
+
+import "fmt"
+
+// Clip is a time range of the video in seconds, only the range is downloaded.
+type Clip struct {
+	Start int
+	End   int
+}
+
+func (c *Clip) Duration() int {
+	return c.End - c.Start
+}
+
+func (c *Clip) String() string {
+	return FormatTimestamp(c.Start) + "-" + FormatTimestamp(c.End)
+}
+
+// FormatTimestamp formats seconds as h:mm:ss or m:ss.
+func FormatTimestamp(seconds int) string {
+	h, m, s := seconds/3600, seconds/60%60, seconds%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+
+	return fmt.Sprintf("%d:%02d", m, s)
+}
@@ -0,0 +1,7 @@
+package models
+
+// GroupSettings are adjusted by group admins, empty fields fall back to the config.
+type GroupSettings struct {
+	Mode    string
+	Quality string
+}
@@ -0,0 +1,8 @@
+package models
+
+// HealthCheck is a result of a readiness check, Info describes the checked component if it is ready
+type HealthCheck struct {
+	Name string
+	Info string
+	Err  error
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+type JobStatus string
+
+const (
+	JobPending JobStatus = "pending"
+	JobRunning JobStatus = "running"
+)
+
+type Job struct {
+	ID     string
+	UserID int64
+	ChatID int64
+	Status JobStatus
+
+	// Username of the user, so bans by username find the user's jobs
+	Username string
+
+	// StatusMessageID is a bot message edited to report the job progress
+	StatusMessageID int
+	// InlineMessageID is set for jobs started from inline mode, the inline message is edited instead of sending a file
+	InlineMessageID string
+
+	// jobs of the same batch, e.g. playlist entries, are processed sequentially
+	BatchID    string
+	BatchIndex int
+	BatchSize  int
+
+	VideoOption VideoOption
+
+	CreatedAt time.Time
+}
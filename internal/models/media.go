@@ -0,0 +1,21 @@
+package models
+
+// CachedMedia is a reference to a document already uploaded to Telegram.
+// It implements message.FileLocation, so it can be sent again without uploading.
+type CachedMedia struct {
+	ID            int64
+	AccessHash    int64
+	FileReference []byte
+}
+
+func (m *CachedMedia) GetID() int64 {
+	return m.ID
+}
+
+func (m *CachedMedia) GetAccessHash() int64 {
+	return m.AccessHash
+}
+
+func (m *CachedMedia) GetFileReference() []byte {
+	return m.FileReference
+}
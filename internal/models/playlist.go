@@ -0,0 +1,31 @@
+package models
+
+type PlaylistInfo struct {
+	ID    string
+	URL   string
+	Title string
+
+	Entries []PlaylistEntry
+	// Total is a number of entries in the playlist, it is greater than len(Entries) if the playlist is truncated
+	Total int
+}
+
+type PlaylistEntry struct {
+	ID        string
+	Extractor string
+	URL       string
+	Title     string
+	Uploader  string
+	ThumbURL  string
+	Duration  int
+}
+
+// Duration returns total duration of the playlist entries in seconds.
+func (p *PlaylistInfo) Duration() int {
+	var duration int
+	for _, entry := range p.Entries {
+		duration += entry.Duration
+	}
+
+	return duration
+}
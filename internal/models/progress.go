@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Progress is a state of the media download reported by the downloader.
+type Progress struct {
+	Downloaded uint64
+	Total      uint64
+	Speed      float64 // bytes per second
+	ETA        time.Duration
+}
+
+func (p Progress) Percent() float64 {
+	if p.Total == 0 {
+		return 0
+	}
+
+	percent := float64(p.Downloaded) / float64(p.Total) * 100
+	if percent > 100 {
+		return 100
+	}
+
+	return percent
+}
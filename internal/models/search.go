@@ -0,0 +1,7 @@
+package models
+
+type SearchResult struct {
+	ID      string
+	Query   string
+	Entries []PlaylistEntry
+}
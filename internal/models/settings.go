@@ -0,0 +1,22 @@
+package models
+
+const (
+	// CaptionFull is "<quality> - <title>"
+	CaptionFull  = "full"
+	CaptionTitle = "title"
+	CaptionNone  = "none"
+)
+
+// QualityAudio means audio of the preferred codec is downloaded by default.
+const QualityAudio = "audio"
+
+type UserSettings struct {
+	// Quality is a video height like p720 or QualityAudio
+	Quality    string
+	AudioCodec string
+	// AutoDownload skips the menu and downloads the default quality
+	AutoDownload bool
+	// SubtitleLang is embedded into videos when available, empty means no subtitles
+	SubtitleLang string
+	CaptionStyle string
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// DailyStats holds counters of a day by extractor name
+type DailyStats struct {
+	Date       string
+	Extractors map[string]*ExtractorStats
+}
+
+type ExtractorStats struct {
+	Downloads      int
+	DownloadBytes  uint64
+	DownloadErrors int
+
+	Uploads      int
+	UploadBytes  uint64
+	UploadErrors int
+}
+
+// KnownUser is a user, who has used the bot in a private chat
+type KnownUser struct {
+	ID        int64
+	Username  string
+	FirstSeen time.Time
+}
+
+// Activity is a download or upload of the job in progress
+type Activity struct {
+	Job       *Job
+	Stage     string
+	StartedAt time.Time
+}
@@ -0,0 +1,55 @@
+package models
+
+// SubtitleTrack is a subtitle language available for the video.
+type SubtitleTrack struct {
+	Lang string
+	Name string
+	// Auto is set for automatically generated captions
+	Auto bool
+}
+
+// Label returns the language name, auto captions are marked.
+func (t *SubtitleTrack) Label() string {
+	label := t.Name
+	if len(label) == 0 {
+		label = t.Lang
+	}
+	if t.Auto {
+		label += " (auto)"
+	}
+
+	return label
+}
+
+type SubtitleMode struct {
+	Name  string
+	Label string
+	// Ext is set when subtitles are sent as a file
+	Ext  string
+	MIME string
+}
+
+var (
+	SubtitleModeSRT  = SubtitleMode{Name: "srt", Label: "📄 .srt file", Ext: "srt", MIME: "application/x-subrip"}
+	SubtitleModeVTT  = SubtitleMode{Name: "vtt", Label: "📄 .vtt file", Ext: "vtt", MIME: "text/vtt"}
+	SubtitleModeSoft = SubtitleMode{Name: "soft", Label: "💬 embedded into video"}
+	SubtitleModeBurn = SubtitleMode{Name: "burn", Label: "🔥 burned into video"}
+
+	SubtitleModes = []SubtitleMode{SubtitleModeSRT, SubtitleModeVTT, SubtitleModeSoft, SubtitleModeBurn}
+)
+
+// File reports whether subtitles are sent as a document instead of a video.
+func (m SubtitleMode) File() bool {
+	return len(m.Ext) > 0
+}
+
+// SubtitleModeByName returns the mode by its name, srt file is returned for unknown names.
+func SubtitleModeByName(name string) SubtitleMode {
+	for _, mode := range SubtitleModes {
+		if mode.Name == name {
+			return mode
+		}
+	}
+
+	return SubtitleModeSRT
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Usage counts user downloads within fixed hour and day windows.
+type Usage struct {
+	HourStart     time.Time
+	HourDownloads int
+
+	DayStart time.Time
+	DayBytes uint64
+}
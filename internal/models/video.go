@@ -1,14 +1,88 @@
 package models
 
+import "time"
+
+// VideoInfo is parsed from the yt-dlp info dict.
+// Bulky fields are not serialized, since the info is copied into every stored VideoOption.
 type VideoInfo struct {
-	URL      string
-	Title    string
-	ThumbURL string
+	// ID is a canonical video ID within the extractor
+	ID        string
+	Extractor string
+
+	URL        string
+	WebpageURL string
+	Title      string
+	ThumbURL   string
+
+	// Artist and Track are used to tag audio files
+	Artist string
+	Track  string
+	Album  string
+
+	Uploader   string
+	UploaderID string
+	Channel    string
+	ChannelID  string
+	UploadDate time.Time
+
+	ViewCount int64
+	LikeCount int64
+
+	Description string `json:"-"`
 
 	Duration int
+	Width    int
+	Height   int
 
 	Vertical bool
 	Youtube  bool
+
+	Chapters          []Chapter
+	Subtitles         map[string][]Subtitle `json:"-"`
+	AutomaticCaptions map[string][]Subtitle `json:"-"`
+	Formats           []Format              `json:"-"`
+}
+
+type Chapter struct {
+	Title     string
+	StartTime float64
+	EndTime   float64
+}
+
+type Subtitle struct {
+	Ext  string
+	URL  string
+	Name string
+}
+
+type Format struct {
+	ID         string
+	Ext        string
+	Resolution string
+	Width      int
+	Height     int
+
+	VCodec   string
+	ACodec   string
+	AudioExt string
+	ABR      float64
+	ASR      int
+	TBR      float64
+
+	Filesize uint64
+}
+
+// HasAudio reports whether the format contains audio, unknown codec is considered as audio.
+func (f *Format) HasAudio() bool {
+	return f.ABR > 0 || f.ASR > 0 || f.ACodec != "none" || f.AudioExt != "none"
+}
+
+func (f *Format) AudioOnly() bool {
+	return f.VCodec == "none" && len(f.ACodec) > 0 && f.ACodec != "none"
+}
+
+func (f *Format) VideoOnly() bool {
+	return f.ACodec == "none" && len(f.VCodec) > 0 && f.VCodec != "none"
 }
 
 type VideoOption struct {
@@ -18,5 +92,34 @@ type VideoOption struct {
 	Size     uint64
 	Audio    bool
 
+	// AudioCodec is a name of models.AudioCodec the audio is converted to
+	AudioCodec string
+
+	// Oversized is set when the expected file size exceeds Telegram upload limit
+	Oversized bool
+
 	VideoInfo VideoInfo
+
+	// Playlist is set when the option downloads all entries of the playlist
+	Playlist *PlaylistInfo
+
+	// SubtitleTracks is set for the option, which opens subtitle languages menu
+	SubtitleTracks []SubtitleTrack
+	// Subtitle is a chosen subtitle track, the option opens delivery modes menu until SubtitleMode is set
+	Subtitle     *SubtitleTrack
+	SubtitleMode string
+
+	// Clip is set when only a part of the video is downloaded
+	Clip *Clip
+
+	// SplitChapters is set when the audio is sent as a track per chapter
+	SplitChapters bool
+
+	// CaptionStyle is a caption of the sent video, see Caption* constants
+	CaptionStyle string
+}
+
+// SubtitleFile reports whether the option downloads only subtitles as a file.
+func (o *VideoOption) SubtitleFile() bool {
+	return o.Subtitle != nil && len(o.SubtitleMode) > 0 && SubtitleModeByName(o.SubtitleMode).File()
 }
@@ -0,0 +1,94 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
+	"github.com/pkg/errors"
+)
+
+const binary = "ffmpeg"
+
+// Split cuts the media file into playable segments of segmentSeconds length without re-encoding
+// and returns paths of the segments in order.
+func Split(ctx context.Context, path string, segmentSeconds int) ([]string, error) {
+	ext := filepath.Ext(path)
+	pattern := strings.TrimSuffix(path, ext) + "_part%03d" + ext
+
+	err := run(ctx,
+		"-i", path,
+		"-map", "0",
+		"-c", "copy",
+		"-f", "segment",
+		"-segment_time", strconv.Itoa(segmentSeconds),
+		"-reset_timestamps", "1",
+		pattern,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	parts, err := filepath.Glob(strings.TrimSuffix(path, ext) + "_part*" + ext)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(parts)
+
+	return parts, nil
+}
+
+// Cut copies the range of the media file between start and end seconds without re-encoding,
+// metadata are "key=value" tags of the output file.
+func Cut(ctx context.Context, path, outPath string, start, end float64, metadata ...string) error {
+	args := []string{
+		"-ss", strconv.FormatFloat(start, 'f', 3, 64),
+		"-to", strconv.FormatFloat(end, 'f', 3, 64),
+		"-i", path,
+		"-map", "0",
+		"-c", "copy",
+	}
+	for _, m := range metadata {
+		args = append(args, "-metadata", m)
+	}
+
+	return run(ctx, append(args, outPath)...)
+}
+
+// BurnSubtitles renders subtitles onto the video frames, so they are shown by any player.
+func BurnSubtitles(ctx context.Context, videoPath, subtitlesPath, outPath string) error {
+	return run(ctx,
+		"-i", videoPath,
+		"-vf", "subtitles="+escapeFilterArg(subtitlesPath),
+		"-c:v", "libx264",
+		"-preset", "veryfast",
+		"-c:a", "copy",
+		outPath,
+	)
+}
+
+func run(ctx context.Context, args ...string) error {
+	args = append([]string{"-hide_banner", "-loglevel", "error", "-y"}, args...)
+
+	log.Logger.Infow("ffmpeg arguments", "args", args)
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("ffmpeg failed: %s", strings.TrimSpace(stderr.String())))
+	}
+
+	return nil
+}
+
+func escapeFilterArg(arg string) string {
+	return strings.NewReplacer(`\`, `\\`, `:`, `\:`, `'`, `\'`, `,`, `\,`).Replace(arg)
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "ytbot"
+
+var (
+	// bytes buckets from 1 MiB to 4 GiB
+	bytesBuckets = prometheus.ExponentialBuckets(1<<20, 2, 13)
+
+	VideoInfoDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "video_info_duration_seconds",
+		Help:      "Latency of getting video info with yt-dlp.",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 8),
+	}, []string{"result"})
+
+	TransferDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "transfer_duration_seconds",
+		Help:      "Duration of downloads and uploads.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 13),
+	}, []string{"stage", "extractor", "result"})
+
+	TransferBytes = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "transfer_bytes",
+		Help:      "Size of downloaded and uploaded files.",
+		Buckets:   bytesBuckets,
+	}, []string{"stage", "extractor"})
+
+	YtDlpExits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ytdlp_exits_total",
+		Help:      "yt-dlp process exits by exit code, -1 means the process was killed.",
+	}, []string{"code"})
+
+	YtDlpErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ytdlp_errors_total",
+		Help:      "Errors printed by yt-dlp by category.",
+	}, []string{"category"})
+
+	QueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_jobs",
+		Help:      "Jobs in the download queue by state.",
+	}, []string{"state"})
+
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Lookups of stored video options and uploaded media by result.",
+	}, []string{"cache", "result"})
+
+	UserbotConnected = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "userbot_connected",
+		Help:      "1 if the userbot is connected and authorized.",
+	})
+)
+
+// Result returns a label value of the operation result.
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+
+	return "ok"
+}
+
+// CacheResult returns a label value of the cache lookup.
+func CacheResult(found bool) string {
+	if found {
+		return "hit"
+	}
+
+	return "miss"
+}
+
+// Handler serves metrics in Prometheus format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -1,19 +1,38 @@
 package telegram
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/far4599/telegram-bot-youtube-download/internal/config"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
+	"go.uber.org/atomic"
 	"gopkg.in/telebot.v3"
 )
 
+const (
+	ModePolling = "polling"
+	ModeWebhook = "webhook"
+)
+
 type BotClient struct {
-	bot *telebot.Bot
+	bot    *telebot.Bot
+	poller *trackedPoller
 }
 
-func NewBotClient(token string) (*BotClient, error) {
+func NewBotClient(conf *config.Config) (*BotClient, error) {
+	p, err := newPoller(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	poller := &trackedPoller{
+		Poller: p,
+	}
+
 	pref := telebot.Settings{
-		Token:  token,
-		Poller: &telebot.LongPoller{Timeout: 5 * time.Second},
+		Token:  conf.Telegram.Bot.Token,
+		Poller: poller,
 	}
 
 	bot, err := telebot.NewBot(pref)
@@ -22,10 +41,71 @@ func NewBotClient(token string) (*BotClient, error) {
 	}
 
 	return &BotClient{
-		bot: bot,
+		bot:    bot,
+		poller: poller,
 	}, nil
 }
 
+func newPoller(conf *config.Config) (telebot.Poller, error) {
+	switch conf.Telegram.Bot.Mode {
+	case ModePolling:
+		return &telebot.LongPoller{Timeout: 5 * time.Second}, nil
+	case ModeWebhook:
+		webhook := conf.Telegram.Bot.Webhook
+		if len(webhook.Listen) == 0 {
+			return nil, fmt.Errorf("webhook listen address is not set")
+		}
+
+		secretToken := webhook.SecretToken
+		if len(secretToken) == 0 {
+			// the local listener is reachable by the harness only if it knows the token
+			if len(webhook.PublicURL) == 0 {
+				return nil, fmt.Errorf("webhook secret token is required when the public url is not set")
+			}
+
+			token, err := newSecretToken()
+			if err != nil {
+				return nil, err
+			}
+			secretToken = token
+			log.Logger.Info("webhook secret token is not set, a random one is registered")
+		}
+
+		return NewWebhookPoller(webhook.Listen, webhook.PublicURL, secretToken, webhook.TLSCert, webhook.TLSKey), nil
+	default:
+		return nil, fmt.Errorf("unknown bot mode '%s'", conf.Telegram.Bot.Mode)
+	}
+}
+
 func (c *BotClient) Bot() *telebot.Bot {
 	return c.bot
 }
+
+// Failed returns a channel, which receives an error if the bot has stopped receiving updates.
+func (c *BotClient) Failed() <-chan error {
+	if webhook, ok := c.poller.Poller.(*WebhookPoller); ok {
+		return webhook.failed
+	}
+
+	// long polling retries forever
+	return nil
+}
+
+// Polling reports whether the bot receives updates.
+func (c *BotClient) Polling() bool {
+	return c.poller.running.Load()
+}
+
+// trackedPoller marks the time the wrapped poller is running.
+type trackedPoller struct {
+	telebot.Poller
+
+	running atomic.Bool
+}
+
+func (p *trackedPoller) Poll(b *telebot.Bot, dest chan telebot.Update, stop chan struct{}) {
+	p.running.Store(true)
+	defer p.running.Store(false)
+
+	p.Poller.Poll(b, dest, stop)
+}
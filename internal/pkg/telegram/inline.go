@@ -0,0 +1,83 @@
+package telegram
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/gotd/td/tg"
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidInlineMessageID = fmt.Errorf("invalid inline message id")
+
+// EditInlineMessage replaces the inline message sent via the bot with the uploaded document.
+func (c *UserBotClient) EditInlineMessage(ctx context.Context, inlineMessageID string, videoOption *models.VideoOption, media *models.CachedMedia) error {
+	if err := c.Status(); err != nil {
+		return err
+	}
+
+	id, dcID, err := parseInlineMessageID(inlineMessageID)
+	if err != nil {
+		return err
+	}
+
+	// inline messages are edited in the DC they are stored in
+	invoker, err := c.client.DC(ctx, dcID, 1)
+	if err != nil {
+		return errors.Wrapf(err, "failed to connect to DC %d", dcID)
+	}
+	defer invoker.Close()
+
+	req := &tg.MessagesEditInlineBotMessageRequest{
+		ID: id,
+		Media: &tg.InputMediaDocument{
+			ID: &tg.InputDocument{
+				ID:            media.ID,
+				AccessHash:    media.AccessHash,
+				FileReference: media.FileReference,
+			},
+		},
+	}
+	if caption := videoCaption(videoOption); !videoOption.Audio && len(caption) > 0 {
+		req.SetMessage(caption)
+	}
+
+	_, err = tg.NewClient(invoker).MessagesEditInlineBotMessage(ctx, req)
+
+	return err
+}
+
+// parseInlineMessageID decodes Bot API inline_message_id, which is a base64 encoded inputBotInlineMessageID
+// or inputBotInlineMessageID64 without a constructor.
+func parseInlineMessageID(inlineMessageID string) (tg.InputBotInlineMessageIDClass, int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(inlineMessageID, "="))
+	if err != nil {
+		return nil, 0, ErrInvalidInlineMessageID
+	}
+
+	le := binary.LittleEndian
+
+	switch len(data) {
+	case 20:
+		id := &tg.InputBotInlineMessageID{
+			DCID:       int(int32(le.Uint32(data[0:]))),
+			ID:         int64(le.Uint64(data[4:])),
+			AccessHash: int64(le.Uint64(data[12:])),
+		}
+		return id, id.DCID, nil
+	case 24:
+		id := &tg.InputBotInlineMessageID64{
+			DCID:       int(int32(le.Uint32(data[0:]))),
+			OwnerID:    int64(le.Uint64(data[4:])),
+			ID:         int(int32(le.Uint32(data[12:]))),
+			AccessHash: int64(le.Uint64(data[16:])),
+		}
+		return id, id.DCID, nil
+	}
+
+	return nil, 0, ErrInvalidInlineMessageID
+}
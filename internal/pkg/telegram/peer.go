@@ -0,0 +1,18 @@
+package telegram
+
+import "github.com/gotd/td/tg"
+
+// channelIDOffset is added to channel and supergroup IDs by Bot API, e.g. -1001234567890.
+const channelIDOffset = -1000000000000
+
+// PeerFromChatID converts Bot API chat ID to MTProto peer. Access hash is not required for peers known to the bot.
+func PeerFromChatID(chatID int64) tg.InputPeerClass {
+	switch {
+	case chatID > 0:
+		return &tg.InputPeerUser{UserID: chatID}
+	case chatID < channelIDOffset:
+		return &tg.InputPeerChannel{ChannelID: channelIDOffset - chatID}
+	default:
+		return &tg.InputPeerChat{ChatID: -chatID}
+	}
+}
@@ -5,40 +5,90 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/far4599/telegram-bot-youtube-download/internal/config"
 	"github.com/far4599/telegram-bot-youtube-download/internal/models"
 	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/metrics"
 	"github.com/gotd/td/session"
 	"github.com/gotd/td/telegram"
 	"github.com/gotd/td/telegram/message"
 	"github.com/gotd/td/telegram/message/styling"
+	"github.com/gotd/td/telegram/message/unpack"
 	"github.com/gotd/td/telegram/uploader"
 	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
 	"github.com/pkg/errors"
+	"go.uber.org/atomic"
 )
 
+var ErrUserbotNotReady = fmt.Errorf("userbot is not connected yet")
+
+const readyCheckInterval = time.Second
+
 type UserBotClient struct {
 	conf *config.Config
 
 	client *telegram.Client
+
+	authorized atomic.Bool
+	// err is set when the client has stopped
+	err atomic.Error
 }
 
+// NewUserBotClient starts the client in background, failure of the client is reported by Status.
 func NewUserBotClient(ctx context.Context, conf *config.Config) *UserBotClient {
 	c := &UserBotClient{
 		conf: conf,
 	}
 
 	go func() {
-		if err := c.run(ctx); err != nil {
-			log.Logger.Fatal(err)
+		err := c.run(ctx)
+		if err == nil || ctx.Err() != nil {
+			return
 		}
+
+		log.Logger.Errorw("userbot stopped", "error", err)
+		c.err.Store(err)
 	}()
 
 	return c
 }
 
+// Status returns nil if the userbot is authorized and may upload files.
+func (c *UserBotClient) Status() error {
+	if err := c.err.Load(); err != nil {
+		return err
+	}
+
+	if !c.authorized.Load() {
+		return ErrUserbotNotReady
+	}
+
+	return nil
+}
+
+// WaitReady blocks until the userbot is authorized. An error is returned if ctx is done or the userbot has stopped.
+func (c *UserBotClient) WaitReady(ctx context.Context) error {
+	ticker := time.NewTicker(readyCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		err := c.Status()
+		if !errors.Is(err, ErrUserbotNotReady) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
 func (c *UserBotClient) run(ctx context.Context) error {
 	sessionDir := c.conf.Telegram.App.SessionDir
 	if err := os.MkdirAll(sessionDir, 0700); err != nil {
@@ -58,6 +108,8 @@ func (c *UserBotClient) run(ctx context.Context) error {
 
 	c.client = telegram.NewClient(c.conf.Telegram.App.ID, c.conf.Telegram.App.Hash, opts)
 
+	defer metrics.UserbotConnected.Set(0)
+
 	return c.client.Run(ctx, func(ctx context.Context) error {
 		status, err := c.client.Auth().Status(ctx)
 		if err != nil {
@@ -71,12 +123,20 @@ func (c *UserBotClient) run(ctx context.Context) error {
 		}
 
 		log.Logger.Info("userbot connected")
+		metrics.UserbotConnected.Set(1)
+		c.authorized.Store(true)
+		defer c.authorized.Store(false)
 
 		return telegram.RunUntilCanceled(ctx, c.client)
 	})
 }
 
-func (c *UserBotClient) UploadFile(ctx context.Context, to tg.InputPeerClass, videoOption *models.VideoOption, path string) error {
+// UploadFile uploads the file and sends it to the peer, onProgress is called with upload percent and may be nil.
+func (c *UserBotClient) UploadFile(ctx context.Context, to tg.InputPeerClass, videoOption *models.VideoOption, path string, onProgress func(percent int32)) (*models.CachedMedia, error) {
+	if err := c.Status(); err != nil {
+		return nil, err
+	}
+
 	api := tg.NewClient(c.client)
 	u := uploader.NewUploader(api)
 	s := message.NewSender(api).WithUploader(u)
@@ -86,7 +146,7 @@ func (c *UserBotClient) UploadFile(ctx context.Context, to tg.InputPeerClass, vi
 
 	target := s.To(to)
 	if target == nil {
-		return nil
+		return nil, nil
 	}
 
 	defer func() {
@@ -98,7 +158,10 @@ func (c *UserBotClient) UploadFile(ctx context.Context, to tg.InputPeerClass, vi
 	go func() {
 		for progress := range uploaderProgress.ProgressChan() {
 			log.Logger.Debugw("upload progress changed", "progress", progress)
-			if videoOption.Audio {
+			if onProgress != nil {
+				onProgress(progress)
+			}
+			if videoOption.Audio || videoOption.SubtitleFile() {
 				_ = target.TypingAction().UploadDocument(ctx, int(progress))
 			} else {
 				_ = target.TypingAction().UploadVideo(ctx, int(progress))
@@ -108,23 +171,158 @@ func (c *UserBotClient) UploadFile(ctx context.Context, to tg.InputPeerClass, vi
 
 	f, err := u.WithProgress(uploaderProgress).FromPath(ctx, path)
 	if err != nil {
-		return errors.Wrap(err, fmt.Sprintf("failed to upload '%s'", path))
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to upload '%s'", path))
+	}
+
+	updates, err := target.Media(ctx, uploadedMedia(videoOption, f))
+	if err != nil {
+		return nil, err
+	}
+
+	return sentMedia(updates), nil
+}
+
+// UploadMedia uploads the file without sending it, so the document may be attached to an inline message.
+func (c *UserBotClient) UploadMedia(ctx context.Context, to tg.InputPeerClass, videoOption *models.VideoOption, path string, onProgress func(percent int32)) (*models.CachedMedia, error) {
+	if err := c.Status(); err != nil {
+		return nil, err
+	}
+
+	api := tg.NewClient(c.client)
+	u := uploader.NewUploader(api)
+
+	uploaderProgress := NewUploaderProgress()
+	defer uploaderProgress.Close()
+
+	go func() {
+		for progress := range uploaderProgress.ProgressChan() {
+			if onProgress != nil {
+				onProgress(progress)
+			}
+		}
+	}()
+
+	f, err := u.WithProgress(uploaderProgress).FromPath(ctx, path)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to upload '%s'", path))
+	}
+
+	media, err := message.NewSender(api).To(to).UploadMedia(ctx, uploadedMedia(videoOption, f))
+	if err != nil {
+		return nil, err
+	}
+
+	return documentMedia(media), nil
+}
+
+func uploadedMedia(videoOption *models.VideoOption, f tg.InputFileClass) message.MediaOption {
+	switch {
+	case videoOption.SubtitleFile():
+		mode := models.SubtitleModeByName(videoOption.SubtitleMode)
+
+		return message.UploadedDocument(f, captionOptions(videoOption)...).
+			MIME(mode.MIME).
+			Filename(videoOption.VideoInfo.Title + "." + videoOption.Subtitle.Lang + "." + mode.Ext)
+	case videoOption.Audio:
+		codec := models.AudioCodecByName(videoOption.AudioCodec)
+		info := videoOption.VideoInfo
+
+		title := info.Track
+		if len(title) == 0 {
+			title = info.Title
+		}
+
+		fileName := title + "." + codec.Ext
+		if len(info.Artist) > 0 {
+			fileName = info.Artist + " - " + fileName
+		}
+
+		return message.UploadedDocument(f).
+			MIME(codec.MIME).
+			Filename(fileName).
+			Audio().
+			Title(title).
+			Performer(info.Artist).
+			DurationSeconds(info.Duration)
+	default:
+		return message.Video(f, captionOptions(videoOption)...)
+	}
+}
+
+// SendCachedFile sends the document uploaded earlier, so it is not downloaded and uploaded again.
+func (c *UserBotClient) SendCachedFile(ctx context.Context, to tg.InputPeerClass, videoOption *models.VideoOption, media *models.CachedMedia) error {
+	if err := c.Status(); err != nil {
+		return err
+	}
+
+	target := message.NewSender(tg.NewClient(c.client)).To(to)
+	if target == nil {
+		return nil
 	}
 
 	var md message.MediaOption
 	if videoOption.Audio {
-		md = message.Audio(f).
-			Title(videoOption.VideoInfo.Title).
-			Performer(videoOption.VideoInfo.URL).
-			DurationSeconds(videoOption.VideoInfo.Duration)
+		md = message.Document(media)
 	} else {
-		md = message.Video(f, styling.Plain(videoOption.Label+" - "+videoOption.VideoInfo.Title))
+		md = message.Document(media, captionOptions(videoOption)...)
+	}
+
+	_, err := target.Media(ctx, md)
+
+	return err
+}
+
+// IsFileReferenceError reports whether the cached file may not be sent anymore and must be uploaded again.
+// Indexed errors like FILE_REFERENCE_0_EXPIRED have the index parsed out of the type.
+func IsFileReferenceError(err error) bool {
+	return tgerr.Is(err, "FILE_REFERENCE_EXPIRED", "FILE_REFERENCE_INVALID")
+}
+
+func videoCaption(videoOption *models.VideoOption) string {
+	switch videoOption.CaptionStyle {
+	case models.CaptionNone:
+		return ""
+	case models.CaptionTitle:
+		return videoOption.VideoInfo.Title
+	default:
+		return videoOption.Label + " - " + videoOption.VideoInfo.Title
+	}
+}
+
+func captionOptions(videoOption *models.VideoOption) []styling.StyledTextOption {
+	caption := videoCaption(videoOption)
+	if len(caption) == 0 {
+		return nil
 	}
 
-	_, err = target.Media(ctx, md)
+	return []styling.StyledTextOption{styling.Plain(caption)}
+}
+
+// sentMedia extracts a reference to the document attached to the sent message.
+func sentMedia(updates tg.UpdatesClass) *models.CachedMedia {
+	msg, err := unpack.Message(updates, nil)
 	if err != nil {
-		return err
+		log.Logger.Debugw("failed to unpack sent message", "error", err)
+		return nil
 	}
 
-	return nil
+	return documentMedia(msg.Media)
+}
+
+func documentMedia(media tg.MessageMediaClass) *models.CachedMedia {
+	doc, ok := media.(*tg.MessageMediaDocument)
+	if !ok {
+		return nil
+	}
+
+	d, ok := doc.Document.AsNotEmpty()
+	if !ok {
+		return nil
+	}
+
+	return &models.CachedMedia{
+		ID:            d.ID,
+		AccessHash:    d.AccessHash,
+		FileReference: d.FileReference,
+	}
 }
@@ -0,0 +1,66 @@
+package telegram
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gotd/td/tgerr"
+	"github.com/pkg/errors"
+)
+
+func TestIsFileReferenceError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "expired", err: tgerr.New(400, "FILE_REFERENCE_EXPIRED"), want: true},
+		{name: "indexed", err: tgerr.New(400, "FILE_REFERENCE_0_EXPIRED"), want: true},
+		{name: "invalid wrapped", err: errors.Wrap(tgerr.New(400, "FILE_REFERENCE_INVALID"), "send media"), want: true},
+		{name: "flood", err: tgerr.New(420, "FLOOD_WAIT_30")},
+		{name: "userbot not ready", err: ErrUserbotNotReady},
+		{name: "timeout", err: context.DeadlineExceeded},
+		{name: "nil"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsFileReferenceError(tt.err); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitReady(t *testing.T) {
+	c := &UserBotClient{}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		c.authorized.Store(true)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := c.WaitReady(ctx); err != nil {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestWaitReadyStopped(t *testing.T) {
+	c := &UserBotClient{}
+	c.err.Store(errors.New("failed to login as userbot"))
+
+	if err := c.WaitReady(context.Background()); err == nil {
+		t.Error("expected an error of stopped userbot")
+	}
+
+	c = &UserBotClient{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := c.WaitReady(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+}
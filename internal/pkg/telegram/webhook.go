@@ -0,0 +1,148 @@
+package telegram
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
+	"github.com/pkg/errors"
+	"gopkg.in/telebot.v3"
+)
+
+const SecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// WebhookPoller receives updates Telegram posts to the listener. The webhook is registered on start and removed on stop.
+// If PublicURL is empty, the webhook is not registered, so synthetic updates may be posted to the listener locally.
+// Updates without the secret token are rejected.
+type WebhookPoller struct {
+	Listen      string
+	PublicURL   string
+	SecretToken string
+	TLSCert     string
+	TLSKey      string
+
+	// failed receives the error, which stopped the poller
+	failed chan error
+}
+
+func NewWebhookPoller(listen, publicURL, secretToken, tlsCert, tlsKey string) *WebhookPoller {
+	return &WebhookPoller{
+		Listen:      listen,
+		PublicURL:   publicURL,
+		SecretToken: secretToken,
+		TLSCert:     tlsCert,
+		TLSKey:      tlsKey,
+		failed:      make(chan error, 1),
+	}
+}
+
+func (p *WebhookPoller) Poll(b *telebot.Bot, dest chan telebot.Update, stop chan struct{}) {
+	if len(p.PublicURL) > 0 {
+		if err := b.SetWebhook(p.webhook()); err != nil {
+			p.fail(errors.Wrapf(err, "failed to register webhook '%s'", p.PublicURL))
+			return
+		}
+		log.Logger.Infow("webhook registered", "url", p.PublicURL)
+
+		defer func() {
+			if err := b.RemoveWebhook(); err != nil {
+				log.Logger.Errorw("failed to remove webhook", "url", p.PublicURL, "error", err)
+			}
+		}()
+	}
+
+	server := &http.Server{
+		Addr:              p.Listen,
+		Handler:           p.handler(dest, stop),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-stop
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_ = server.Shutdown(ctx)
+	}()
+
+	log.Logger.Infow("webhook server started", "addr", p.Listen)
+
+	var err error
+	if len(p.TLSCert) > 0 {
+		err = server.ListenAndServeTLS(p.TLSCert, p.TLSKey)
+	} else {
+		err = server.ListenAndServe()
+	}
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		p.fail(errors.Wrapf(err, "failed to serve webhook on '%s'", p.Listen))
+	}
+}
+
+func (p *WebhookPoller) fail(err error) {
+	select {
+	case p.failed <- err:
+	default:
+	}
+}
+
+// newSecretToken returns a random token, which is valid for X-Telegram-Bot-Api-Secret-Token.
+func newSecretToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "failed to generate webhook secret token")
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func (p *WebhookPoller) webhook() *telebot.Webhook {
+	webhook := &telebot.Webhook{
+		SecretToken: p.SecretToken,
+		Endpoint: &telebot.WebhookEndpoint{
+			PublicURL: p.PublicURL,
+		},
+	}
+
+	// the certificate is uploaded, so Telegram trusts a self-signed one
+	if len(p.TLSCert) > 0 {
+		webhook.Endpoint.Cert = p.TLSCert
+	}
+
+	return webhook
+}
+
+// handler passes posted updates to the bot, requests without the secret token are rejected.
+func (p *WebhookPoller) handler(dest chan<- telebot.Update, stop <-chan struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		token := r.Header.Get(SecretTokenHeader)
+		if len(p.SecretToken) == 0 || subtle.ConstantTimeCompare([]byte(token), []byte(p.SecretToken)) != 1 {
+			log.Logger.Warnw("webhook request with invalid secret token", "remote", r.RemoteAddr)
+			http.Error(w, "invalid secret token", http.StatusUnauthorized)
+			return
+		}
+
+		var update telebot.Update
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			http.Error(w, "invalid update", http.StatusBadRequest)
+			return
+		}
+
+		select {
+		case dest <- update:
+		case <-stop:
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		}
+	}
+}
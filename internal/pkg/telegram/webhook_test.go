@@ -0,0 +1,98 @@
+package telegram
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gopkg.in/telebot.v3"
+)
+
+const testUpdate = `{"update_id":1,"message":{"message_id":2,"date":1,"from":{"id":5,"first_name":"h"},"chat":{"id":5,"type":"private"},"text":"https://youtu.be/x"}}`
+
+func TestWebhookPollerHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		body   string
+		status int
+		passed bool
+	}{
+		{name: "valid token", token: "secret", body: testUpdate, status: http.StatusOK, passed: true},
+		{name: "missing token", body: testUpdate, status: http.StatusUnauthorized},
+		{name: "invalid token", token: "wrong", body: testUpdate, status: http.StatusUnauthorized},
+		{name: "invalid update", token: "secret", body: "{", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := make(chan telebot.Update, 1)
+			p := NewWebhookPoller("", "", "secret", "", "")
+
+			server := httptest.NewServer(p.handler(dest, make(chan struct{})))
+			defer server.Close()
+
+			req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tt.token) > 0 {
+				req.Header.Set(SecretTokenHeader, tt.token)
+			}
+
+			resp, err := server.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+
+			select {
+			case update := <-dest:
+				if !tt.passed {
+					t.Fatalf("update %d passed, want rejected", update.ID)
+				}
+				if update.Message == nil || update.Message.Sender.ID != 5 || update.Message.Text != "https://youtu.be/x" {
+					t.Errorf("unexpected update %+v", update.Message)
+				}
+			default:
+				if tt.passed {
+					t.Fatal("update did not reach the bot")
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookPollerHandlerWithoutToken(t *testing.T) {
+	dest := make(chan telebot.Update, 1)
+	p := NewWebhookPoller("", "", "", "", "")
+
+	rec := httptest.NewRecorder()
+	p.handler(dest, make(chan struct{}))(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(testUpdate)))
+
+	if rec.Code != http.StatusUnauthorized || len(dest) != 0 {
+		t.Errorf("status = %d, updates = %d, want rejected", rec.Code, len(dest))
+	}
+}
+
+func TestWebhookPollerFailsOnBusyAddress(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	p := NewWebhookPoller(strings.TrimPrefix(server.URL, "http://"), "", "secret", "", "")
+	p.Poll(nil, make(chan telebot.Update), make(chan struct{}))
+
+	select {
+	case err := <-p.failed:
+		if err == nil {
+			t.Fatal("nil error")
+		}
+	default:
+		t.Fatal("poller did not report the failure")
+	}
+}
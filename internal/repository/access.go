@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"encoding/json"
+)
+
+const accessBucket = "access"
+
+// AccessRepository stores access rules set by admins at runtime.
+// Subject is a key like "user:123", "username:name" or "chat:-100123".
+type AccessRepository struct {
+	db *BoltDB
+}
+
+func NewAccessRepository(db *BoltDB) *AccessRepository {
+	return &AccessRepository{
+		db: db,
+	}
+}
+
+func (r *AccessRepository) Set(subject string, allowed bool) error {
+	return r.db.put(accessBucket, subject, allowed)
+}
+
+func (r *AccessRepository) Get(subject string) (allowed bool, found bool, err error) {
+	found, err = r.db.get(accessBucket, subject, &allowed)
+
+	return allowed, found, err
+}
+
+// HasAllowed reports whether any subject is allowed explicitly.
+func (r *AccessRepository) HasAllowed() (bool, error) {
+	var hasAllowed bool
+	err := r.db.forEach(accessBucket, func(_ string, data []byte) error {
+		var allowed bool
+		if err := json.Unmarshal(data, &allowed); err != nil {
+			return err
+		}
+
+		hasAllowed = hasAllowed || allowed
+
+		return nil
+	})
+
+	return hasAllowed, err
+}
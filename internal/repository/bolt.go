@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.etcd.io/bbolt"
+)
+
+type BoltDB struct {
+	db *bbolt.DB
+}
+
+func NewBoltDB(path string) (*BoltDB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, errors.Wrapf(err, "failed to create storage dir for '%s'", path)
+	}
+
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open storage '%s'", path)
+	}
+
+	return &BoltDB{
+		db: db,
+	}, nil
+}
+
+func (b *BoltDB) Close() error {
+	return b.db.Close()
+}
+
+func (b *BoltDB) put(bucket, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bkt, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+
+		return bkt.Put([]byte(key), data)
+	})
+}
+
+// putAll stores the values by their keys in a single transaction.
+func (b *BoltDB) putAll(bucket string, values map[string]any) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	data := make(map[string][]byte, len(values))
+	for key, v := range values {
+		d, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data[key] = d
+	}
+
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bkt, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+
+		for key, d := range data {
+			if err = bkt.Put([]byte(key), d); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (b *BoltDB) get(bucket, key string, v any) (bool, error) {
+	var found bool
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket([]byte(bucket))
+		if bkt == nil {
+			return nil
+		}
+
+		data := bkt.Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		found = true
+
+		return json.Unmarshal(data, v)
+	})
+
+	return found, err
+}
+
+func (b *BoltDB) delete(bucket, key string) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket([]byte(bucket))
+		if bkt == nil {
+			return nil
+		}
+
+		return bkt.Delete([]byte(key))
+	})
+}
+
+func (b *BoltDB) forEach(bucket string, fn func(key string, data []byte) error) error {
+	return b.db.View(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket([]byte(bucket))
+		if bkt == nil {
+			return nil
+		}
+
+		return bkt.ForEach(func(k, v []byte) error {
+			return fn(string(k), v)
+		})
+	})
+}
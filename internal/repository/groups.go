@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"strconv"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+)
+
+const groupsBucket = "groups"
+
+type GroupRepository struct {
+	db *BoltDB
+}
+
+func NewGroupRepository(db *BoltDB) *GroupRepository {
+	return &GroupRepository{
+		db: db,
+	}
+}
+
+func (r *GroupRepository) Get(chatID int64) (*models.GroupSettings, error) {
+	settings := new(models.GroupSettings)
+	if _, err := r.db.get(groupsBucket, strconv.FormatInt(chatID, 10), settings); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+func (r *GroupRepository) Save(chatID int64, settings *models.GroupSettings) error {
+	return r.db.put(groupsBucket, strconv.FormatInt(chatID, 10), settings)
+}
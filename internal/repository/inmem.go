@@ -1,20 +1,57 @@
 package repository
 
 import (
+	"time"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
 	lru "github.com/hashicorp/golang-lru"
 )
 
 type InMemRepository struct {
-	*lru.Cache
+	cache *lru.Cache
+	ttl   time.Duration
+}
+
+type inMemEntry struct {
+	opt       *models.VideoOption
+	expiresAt time.Time
 }
 
-func NewInMemRepository() (*InMemRepository, error) {
+func NewInMemRepository(ttl time.Duration) (*InMemRepository, error) {
 	cache, err := lru.New(10_000)
 	if err != nil {
 		return nil, err
 	}
 
 	return &InMemRepository{
-		Cache: cache,
+		cache: cache,
+		ttl:   ttl,
 	}, nil
 }
+
+func (r *InMemRepository) Get(id string) (*models.VideoOption, bool, error) {
+	cached, ok := r.cache.Get(id)
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := cached.(*inMemEntry)
+	if time.Now().After(entry.expiresAt) {
+		r.cache.Remove(id)
+		return nil, false, nil
+	}
+
+	return entry.opt, true, nil
+}
+
+func (r *InMemRepository) Put(opts ...*models.VideoOption) error {
+	expiresAt := time.Now().Add(r.ttl)
+	for _, opt := range opts {
+		r.cache.Add(opt.ID, &inMemEntry{
+			opt:       opt,
+			expiresAt: expiresAt,
+		})
+	}
+
+	return nil
+}
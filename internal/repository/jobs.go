@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+)
+
+const jobsBucket = "jobs"
+
+type JobRepository struct {
+	db *BoltDB
+}
+
+func NewJobRepository(db *BoltDB) *JobRepository {
+	return &JobRepository{
+		db: db,
+	}
+}
+
+func (r *JobRepository) Save(job *models.Job) error {
+	return r.db.put(jobsBucket, job.ID, job)
+}
+
+func (r *JobRepository) Delete(id string) error {
+	return r.db.delete(jobsBucket, id)
+}
+
+// List returns all persisted jobs ordered by creation time.
+func (r *JobRepository) List() ([]*models.Job, error) {
+	var jobs []*models.Job
+	err := r.db.forEach(jobsBucket, func(_ string, data []byte) error {
+		job := new(models.Job)
+		if err := json.Unmarshal(data, job); err != nil {
+			return err
+		}
+
+		jobs = append(jobs, job)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+
+	return jobs, nil
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+)
+
+const mediaCacheBucket = "media_cache"
+
+type MediaCacheRepository struct {
+	db *BoltDB
+}
+
+func NewMediaCacheRepository(db *BoltDB) *MediaCacheRepository {
+	return &MediaCacheRepository{
+		db: db,
+	}
+}
+
+func (r *MediaCacheRepository) Get(key string) (*models.CachedMedia, bool, error) {
+	media := new(models.CachedMedia)
+	found, err := r.db.get(mediaCacheBucket, key, media)
+	if err != nil || !found {
+		return nil, false, err
+	}
+
+	return media, true, nil
+}
+
+func (r *MediaCacheRepository) Put(key string, media *models.CachedMedia) error {
+	return r.db.put(mediaCacheBucket, key, media)
+}
+
+func (r *MediaCacheRepository) Delete(key string) error {
+	return r.db.delete(mediaCacheBucket, key)
+}
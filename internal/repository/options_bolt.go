@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
+	"go.etcd.io/bbolt"
+)
+
+const optionsBucket = "video_options"
+
+type BoltRepository struct {
+	db  *BoltDB
+	ttl time.Duration
+}
+
+type boltEntry struct {
+	Option    *models.VideoOption
+	ExpiresAt time.Time
+}
+
+func NewBoltRepository(db *BoltDB, ttl time.Duration) *BoltRepository {
+	return &BoltRepository{
+		db:  db,
+		ttl: ttl,
+	}
+}
+
+func (r *BoltRepository) Get(id string) (*models.VideoOption, bool, error) {
+	var entry boltEntry
+	found, err := r.db.get(optionsBucket, id, &entry)
+	if err != nil || !found {
+		return nil, false, err
+	}
+
+	if time.Now().After(entry.ExpiresAt) {
+		return nil, false, r.db.delete(optionsBucket, id)
+	}
+
+	return entry.Option, true, nil
+}
+
+// Put stores the options in a single transaction, since every transaction is synced to disk.
+func (r *BoltRepository) Put(opts ...*models.VideoOption) error {
+	expiresAt := time.Now().Add(r.ttl)
+
+	entries := make(map[string]any, len(opts))
+	for _, opt := range opts {
+		entries[opt.ID] = &boltEntry{
+			Option:    opt,
+			ExpiresAt: expiresAt,
+		}
+	}
+
+	return r.db.putAll(optionsBucket, entries)
+}
+
+// RunCleanup periodically removes expired options until ctx is done.
+func (r *BoltRepository) RunCleanup(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.deleteExpired(); err != nil {
+				log.Logger.Errorw("failed to delete expired video options", "error", err)
+			}
+		}
+	}
+}
+
+func (r *BoltRepository) deleteExpired() error {
+	now := time.Now()
+
+	return r.db.db.Update(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket([]byte(optionsBucket))
+		if bkt == nil {
+			return nil
+		}
+
+		var expired [][]byte
+		err := bkt.ForEach(func(k, v []byte) error {
+			var entry boltEntry
+			if err := json.Unmarshal(v, &entry); err != nil || now.After(entry.ExpiresAt) {
+				expired = append(expired, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			if err = bkt.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+)
+
+func TestBoltRepositoryPut(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "bot.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	repo := NewBoltRepository(db, time.Hour)
+
+	opts := []*models.VideoOption{
+		{ID: "a", Label: "p360"},
+		{ID: "b", Label: "p720"},
+		{ID: "c", Label: "only audio", Audio: true},
+	}
+	if err = repo.Put(opts...); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range opts {
+		got, found, err := repo.Get(want.ID)
+		if err != nil || !found {
+			t.Fatalf("option %s: found %t, error %v", want.ID, found, err)
+		}
+		if got.Label != want.Label || got.Audio != want.Audio {
+			t.Errorf("option %s: got %+v, want %+v", want.ID, got, want)
+		}
+	}
+
+	if err = repo.Put(); err != nil {
+		t.Errorf("empty put: %v", err)
+	}
+}
+
+func TestBoltRepositoryExpired(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "bot.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	repo := NewBoltRepository(db, -time.Second)
+	if err = repo.Put(&models.VideoOption{ID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, found, err := repo.Get("a"); err != nil || found {
+		t.Errorf("expired option: found %t, error %v", found, err)
+	}
+}
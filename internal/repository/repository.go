@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+)
+
+// Repository stores video options referenced by inline buttons.
+type Repository interface {
+	Get(id string) (*models.VideoOption, bool, error)
+	// Put stores the options at once.
+	Put(opts ...*models.VideoOption) error
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// SearchRepository keeps recent search results in memory for pagination, results are not worth persisting.
+type SearchRepository struct {
+	cache *lru.Cache
+}
+
+func NewSearchRepository() (*SearchRepository, error) {
+	cache, err := lru.New(1_000)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SearchRepository{
+		cache: cache,
+	}, nil
+}
+
+func (r *SearchRepository) Get(id string) (*models.SearchResult, bool) {
+	cached, ok := r.cache.Get(id)
+	if !ok {
+		return nil, false
+	}
+
+	return cached.(*models.SearchResult), true
+}
+
+func (r *SearchRepository) Put(result *models.SearchResult) {
+	r.cache.Add(result.ID, result)
+}
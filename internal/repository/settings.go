@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"strconv"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+)
+
+const settingsBucket = "settings"
+
+type SettingsRepository struct {
+	db *BoltDB
+}
+
+func NewSettingsRepository(db *BoltDB) *SettingsRepository {
+	return &SettingsRepository{
+		db: db,
+	}
+}
+
+// Get returns settings of the user, found is false if the user has not changed any.
+func (r *SettingsRepository) Get(userID int64) (*models.UserSettings, bool, error) {
+	settings := new(models.UserSettings)
+	found, err := r.db.get(settingsBucket, strconv.FormatInt(userID, 10), settings)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return settings, found, nil
+}
+
+func (r *SettingsRepository) Save(userID int64, settings *models.UserSettings) error {
+	return r.db.put(settingsBucket, strconv.FormatInt(userID, 10), settings)
+}
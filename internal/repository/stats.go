@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+)
+
+const (
+	statsBucket = "stats"
+	usersBucket = "users"
+)
+
+// StatsRepository stores daily counters by date and IDs of users, who have used the bot.
+type StatsRepository struct {
+	db *BoltDB
+}
+
+func NewStatsRepository(db *BoltDB) *StatsRepository {
+	return &StatsRepository{
+		db: db,
+	}
+}
+
+func (r *StatsRepository) Get(date string) (*models.DailyStats, error) {
+	stats := &models.DailyStats{Date: date}
+	if _, err := r.db.get(statsBucket, date, stats); err != nil {
+		return nil, err
+	}
+
+	if stats.Extractors == nil {
+		stats.Extractors = make(map[string]*models.ExtractorStats)
+	}
+
+	return stats, nil
+}
+
+func (r *StatsRepository) Save(stats *models.DailyStats) error {
+	return r.db.put(statsBucket, stats.Date, stats)
+}
+
+// SaveUser stores the user with the time the user was seen first, only the username of known users is changed.
+func (r *StatsRepository) SaveUser(userID int64, username string) error {
+	key := strconv.FormatInt(userID, 10)
+
+	var data json.RawMessage
+	found, err := r.db.get(usersBucket, key, &data)
+	if err != nil {
+		return err
+	}
+
+	user := &models.KnownUser{ID: userID, FirstSeen: time.Now()}
+	if found {
+		if user = parseKnownUser(userID, data); user.Username == username {
+			return nil
+		}
+	}
+	user.Username = username
+
+	return r.db.put(usersBucket, key, user)
+}
+
+func (r *StatsRepository) Users() ([]*models.KnownUser, error) {
+	var users []*models.KnownUser
+	err := r.db.forEach(usersBucket, func(key string, data []byte) error {
+		userID, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		users = append(users, parseKnownUser(userID, data))
+
+		return nil
+	})
+
+	return users, err
+}
+
+// parseKnownUser reads the user, earlier versions stored the time the user was seen first only.
+func parseKnownUser(userID int64, data []byte) *models.KnownUser {
+	user := &models.KnownUser{ID: userID}
+	if err := json.Unmarshal(data, user); err != nil {
+		_ = json.Unmarshal(data, &user.FirstSeen)
+	}
+	user.ID = userID
+
+	return user
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStatsRepositoryUsers(t *testing.T) {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "bot.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// users saved by earlier versions have the time they were seen first only
+	firstSeen := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err = db.put(usersBucket, "1", firstSeen); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewStatsRepository(db)
+	for _, user := range []struct {
+		id       int64
+		username string
+	}{{1, "alice"}, {2, ""}, {2, "bob"}} {
+		if err = repo.SaveUser(user.id, user.username); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	users, err := repo.Users()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("got %d users", len(users))
+	}
+
+	if u := users[0]; u.ID != 1 || u.Username != "alice" || !u.FirstSeen.Equal(firstSeen) {
+		t.Errorf("got %+v", u)
+	}
+	if u := users[1]; u.ID != 2 || u.Username != "bob" || u.FirstSeen.IsZero() {
+		t.Errorf("got %+v", u)
+	}
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"strconv"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+)
+
+const usageBucket = "usage"
+
+type UsageRepository struct {
+	db *BoltDB
+}
+
+func NewUsageRepository(db *BoltDB) *UsageRepository {
+	return &UsageRepository{
+		db: db,
+	}
+}
+
+func (r *UsageRepository) Get(userID int64) (*models.Usage, error) {
+	usage := new(models.Usage)
+	if _, err := r.db.get(usageBucket, strconv.FormatInt(userID, 10), usage); err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
+
+func (r *UsageRepository) Save(userID int64, usage *models.Usage) error {
+	return r.db.put(usageBucket, strconv.FormatInt(userID, 10), usage)
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/config"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
+	"github.com/far4599/telegram-bot-youtube-download/internal/repository"
+)
+
+var ErrInvalidSubject = fmt.Errorf("expected user ID, @username or group chat ID")
+
+// AccessService decides who may use the bot. Rules set by admins at runtime take precedence over the config.
+// If no one is allowed neither in the config nor at runtime, the bot is open to everyone except denied ones.
+type AccessService struct {
+	conf *config.Config
+	repo *repository.AccessRepository
+}
+
+func NewAccessService(conf *config.Config, repo *repository.AccessRepository) *AccessService {
+	return &AccessService{
+		conf: conf,
+		repo: repo,
+	}
+}
+
+func (s *AccessService) IsAdmin(userID int64) bool {
+	return containsInt64(s.conf.Access.Admins, userID)
+}
+
+// IsAllowed checks the user in the chat, chatID equal to userID means a private chat.
+func (s *AccessService) IsAllowed(userID int64, username string, chatID int64) bool {
+	if s.IsAdmin(userID) {
+		return true
+	}
+
+	subjects := []string{userSubject(userID)}
+	if len(username) > 0 {
+		subjects = append(subjects, usernameSubject(username))
+	}
+	if chatID != userID {
+		subjects = append(subjects, chatSubject(chatID))
+	}
+
+	for _, subject := range subjects {
+		allowed, found, err := s.repo.Get(subject)
+		if err != nil {
+			log.Logger.Errorw("failed to get access rule", "subject", subject, "error", err)
+			return false
+		}
+		if found {
+			return allowed
+		}
+	}
+
+	access := s.conf.Access
+	if containsInt64(access.AllowedUsers, userID) || containsInt64(access.AllowedChats, chatID) {
+		return true
+	}
+	for _, allowedUsername := range access.AllowedUsernames {
+		if len(username) > 0 && strings.EqualFold(strings.TrimPrefix(allowedUsername, "@"), username) {
+			return true
+		}
+	}
+
+	if len(access.AllowedUsers) > 0 || len(access.AllowedUsernames) > 0 || len(access.AllowedChats) > 0 {
+		return false
+	}
+
+	hasAllowed, err := s.repo.HasAllowed()
+	if err != nil {
+		log.Logger.Errorw("failed to check access rules", "error", err)
+		return false
+	}
+
+	return !hasAllowed
+}
+
+// Allow grants access to the subject, which is user ID, @username or negative group chat ID.
+func (s *AccessService) Allow(subject string) error {
+	return s.set(subject, true)
+}
+
+// Deny revokes access from the subject, which is user ID, @username or negative group chat ID.
+func (s *AccessService) Deny(subject string) error {
+	return s.set(subject, false)
+}
+
+func (s *AccessService) set(subject string, allowed bool) error {
+	key, err := parseSubject(subject)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.Set(key, allowed)
+}
+
+func parseSubject(subject string) (string, error) {
+	subject = strings.TrimSpace(subject)
+	if len(subject) == 0 {
+		return "", ErrInvalidSubject
+	}
+
+	if id, err := strconv.ParseInt(subject, 10, 64); err == nil {
+		if id < 0 {
+			return chatSubject(id), nil
+		}
+		return userSubject(id), nil
+	}
+
+	username := strings.TrimPrefix(subject, "@")
+	if len(username) == 0 || strings.ContainsAny(username, " @") {
+		return "", ErrInvalidSubject
+	}
+
+	return usernameSubject(username), nil
+}
+
+func userSubject(id int64) string {
+	return "user:" + strconv.FormatInt(id, 10)
+}
+
+func usernameSubject(username string) string {
+	return "username:" + strings.ToLower(username)
+}
+
+func chatSubject(id int64) string {
+	return "chat:" + strconv.FormatInt(id, 10)
+}
+
+func containsInt64(list []int64, v int64) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+
+	return false
+}
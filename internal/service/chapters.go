@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/ffmpeg"
+)
+
+// maxChapters limits the chapters menu, Telegram does not show more than 100 buttons.
+const maxChapters = 50
+
+// GetChaptersOption returns an option, which downloads the whole audio split into a track per chapter.
+func (s *VideoService) GetChaptersOption(videoInfo *models.VideoInfo, opts []*models.VideoOption) (*models.VideoOption, bool) {
+	if len(videoInfo.Chapters) < 2 {
+		return nil, false
+	}
+
+	opt := models.VideoOption{
+		FormatID:   "bestaudio/best",
+		Audio:      true,
+		AudioCodec: models.AudioCodecMP3.Name,
+		VideoInfo:  *videoInfo,
+	}
+	for _, o := range opts {
+		if o.Audio && o.AudioCodec == models.AudioCodecMP3.Name {
+			opt = *o
+			break
+		}
+	}
+
+	opt.Label = models.AudioCodecMP3.Label + " by chapters"
+	opt.SplitChapters = true
+	s.saveToCache(&opt)
+
+	return &opt, true
+}
+
+// ChapterClip returns the range of the chapter, which fits the video duration.
+func ChapterClip(videoInfo *models.VideoInfo, chapter models.Chapter) *models.Clip {
+	clip := &models.Clip{
+		Start: int(chapter.StartTime),
+		End:   int(chapter.EndTime + 0.5),
+	}
+	if videoInfo.Duration > 0 && clip.End > videoInfo.Duration {
+		clip.End = videoInfo.Duration
+	}
+
+	return clip
+}
+
+// SplitChapters cuts the downloaded audio into a track per chapter and returns options describing each track
+// along with the track paths.
+func (s *VideoService) SplitChapters(ctx context.Context, videoOption *models.VideoOption, path string) ([]models.VideoOption, []string, error) {
+	chapters := videoOption.VideoInfo.Chapters
+	ext := filepath.Ext(path)
+
+	trackOptions := make([]models.VideoOption, 0, len(chapters))
+	paths := make([]string, 0, len(chapters))
+	for i, chapter := range chapters {
+		trackPath := fmt.Sprintf("%s_chapter%03d%s", strings.TrimSuffix(path, ext), i+1, ext)
+
+		err := ffmpeg.Cut(ctx, path, trackPath, chapter.StartTime, chapter.EndTime,
+			"title="+chapter.Title,
+			fmt.Sprintf("track=%d/%d", i+1, len(chapters)),
+			"album="+videoOption.VideoInfo.Title,
+		)
+		if err != nil {
+			removeFiles(append(paths, trackPath))
+			return nil, nil, err
+		}
+
+		trackOption := *videoOption
+		trackOption.Label = fmt.Sprintf("%s chapter %d of %d", models.AudioCodecMP3.Label, i+1, len(chapters))
+		trackOption.VideoInfo.Title = chapter.Title
+		trackOption.VideoInfo.Track = chapter.Title
+		trackOption.VideoInfo.Duration = int(chapter.EndTime - chapter.StartTime)
+
+		trackOptions = append(trackOptions, trackOption)
+		paths = append(paths, trackPath)
+	}
+
+	return trackOptions, paths, nil
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+)
+
+var (
+	ErrInvalidClip = fmt.Errorf("invalid time range, use format like 1:23-2:45")
+
+	clipRangeRegex = regexp.MustCompile(`^(\d+(?::\d{1,2}){0,2})-(\d+(?::\d{1,2}){0,2})$`)
+)
+
+const clipEmoji = "✂️"
+
+// GetClipOptions returns copies of the options, which download only the clip range.
+// Clip without end lasts until the end of the video, the options are returned as is if the duration is unknown.
+func (s *VideoService) GetClipOptions(videoInfo *models.VideoInfo, opts []*models.VideoOption, videoClip *models.Clip) ([]*models.VideoOption, error) {
+	clip := *videoClip
+	if clip.End == 0 {
+		if videoInfo.Duration == 0 {
+			return opts, nil
+		}
+		clip.End = videoInfo.Duration
+	}
+
+	if clip.Start < 0 || clip.Start >= clip.End || (videoInfo.Duration > 0 && clip.End > videoInfo.Duration) {
+		return nil, fmt.Errorf("%w: video is %s long", ErrInvalidClip, models.FormatTimestamp(videoInfo.Duration))
+	}
+
+	result := make([]*models.VideoOption, 0, len(opts))
+	for _, opt := range opts {
+		clipOpt := *opt
+		clipOpt.Clip = &clip
+		clipOpt.Label += " " + clipEmoji + " " + clip.String()
+
+		if videoInfo.Duration > 0 {
+			clipOpt.Size = opt.Size * uint64(clip.Duration()) / uint64(videoInfo.Duration)
+			clipOpt.Oversized = s.IsOversized(clipOpt.Size)
+		}
+
+		result = append(result, &clipOpt)
+	}
+	s.saveToCache(result...)
+
+	return result, nil
+}
+
+// parseClipRange parses ranges like "1:23-2:45" or "83-165".
+func parseClipRange(input string) (*models.Clip, bool) {
+	match := clipRangeRegex.FindStringSubmatch(strings.TrimSpace(input))
+	if match == nil {
+		return nil, false
+	}
+
+	start, err := parseTimestamp(match[1])
+	if err != nil {
+		return nil, false
+	}
+
+	end, err := parseTimestamp(match[2])
+	if err != nil {
+		return nil, false
+	}
+
+	return &models.Clip{Start: start, End: end}, true
+}
+
+// clipFromURL returns a clip starting at the t= parameter of the url, if any. Shared links often have it,
+// so it is only a hint for the trim prompt, not a request to trim.
+func clipFromURL(videoURL string) (*models.Clip, bool) {
+	u, err := url.Parse(videoURL)
+	if err != nil {
+		return nil, false
+	}
+
+	t := u.Query().Get("t")
+	if len(t) == 0 {
+		return nil, false
+	}
+
+	start, err := parseTimestamp(t)
+	if err != nil || start == 0 {
+		return nil, false
+	}
+
+	return &models.Clip{Start: start}, true
+}
+
+// parseTimestamp parses seconds, durations like 1m23s and timestamps like 1:23 or 1:02:03.
+func parseTimestamp(input string) (int, error) {
+	if seconds, err := strconv.Atoi(input); err == nil {
+		return seconds, nil
+	}
+
+	if d, err := time.ParseDuration(input); err == nil {
+		return int(d.Seconds()), nil
+	}
+
+	var seconds int
+	for _, part := range strings.Split(input, ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, ErrInvalidClip
+		}
+
+		seconds = seconds*60 + n
+	}
+
+	return seconds, nil
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/config"
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/far4599/telegram-bot-youtube-download/internal/repository"
+	"gopkg.in/telebot.v3"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{input: "83", want: 83},
+		{input: "1:23", want: 83},
+		{input: "01:02:03", want: 3723},
+		{input: "1m23s", want: 83},
+		{input: "1h2m", want: 3720},
+		{input: "1:xx", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseTimestamp(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseClipRange(t *testing.T) {
+	tests := []struct {
+		input string
+		want  *models.Clip
+	}{
+		{input: "1:23-2:45", want: &models.Clip{Start: 83, End: 165}},
+		{input: "83-165", want: &models.Clip{Start: 83, End: 165}},
+		{input: " 1:00:00-1:30:00 ", want: &models.Clip{Start: 3600, End: 5400}},
+		{input: "1:23"},
+		{input: "1:234-2:45"},
+		{input: "1m-2m"},
+		{input: "https://youtu.be/x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := parseClipRange(tt.input)
+			if ok != (tt.want != nil) {
+				t.Fatalf("got %+v, ok %t", got, ok)
+			}
+			if ok && *got != *tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClipFromURL(t *testing.T) {
+	tests := []struct {
+		url       string
+		wantStart int
+	}{
+		{url: "https://youtu.be/x?t=83", wantStart: 83},
+		{url: "https://www.youtube.com/watch?v=x&t=1m23s", wantStart: 83},
+		{url: "https://www.youtube.com/watch?v=x&t=0"},
+		{url: "https://www.youtube.com/watch?v=x"},
+		{url: "https://youtu.be/x?t=later"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			clip, ok := clipFromURL(tt.url)
+			if ok != (tt.wantStart > 0) {
+				t.Fatalf("got %+v, ok %t", clip, ok)
+			}
+			if ok && (clip.Start != tt.wantStart || clip.End != 0) {
+				t.Errorf("got %+v, want start %d", clip, tt.wantStart)
+			}
+		})
+	}
+}
+
+func TestGetClipOptions(t *testing.T) {
+	repo, err := repository.NewInMemRepository(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := &config.Config{}
+	conf.Upload.MaxSize = 1500
+	s := &VideoService{conf: conf, repo: repo}
+
+	videoInfo := &models.VideoInfo{Duration: 200}
+	opts := []*models.VideoOption{{FormatID: "18", Label: "p360", Size: 2000}}
+
+	tests := []struct {
+		name     string
+		clip     models.Clip
+		wantEnd  int
+		wantSize uint64
+		wantErr  bool
+		// -1 means unknown duration, the options are returned as is
+		duration int
+	}{
+		{name: "range", clip: models.Clip{Start: 50, End: 150}, wantEnd: 150, wantSize: 1000},
+		{name: "until the end", clip: models.Clip{Start: 100}, wantEnd: 200, wantSize: 1000},
+		{name: "until the end of unknown duration", clip: models.Clip{Start: 100}, duration: -1},
+		{name: "end out of video", clip: models.Clip{Start: 100, End: 201}, wantErr: true},
+		{name: "start after end", clip: models.Clip{Start: 150, End: 50}, wantErr: true},
+		{name: "start at the end", clip: models.Clip{Start: 200}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clip := tt.clip
+
+			info := videoInfo
+			if tt.duration < 0 {
+				info = &models.VideoInfo{}
+			}
+
+			clipOpts, err := s.GetClipOptions(info, opts, &clip)
+			if clip != tt.clip {
+				t.Errorf("clip of the caller is changed to %+v", clip)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidClip) {
+					t.Errorf("got error %v, want %v", err, ErrInvalidClip)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(clipOpts) != 1 {
+				t.Fatalf("got %d options", len(clipOpts))
+			}
+			opt := clipOpts[0]
+			if tt.duration < 0 {
+				if opt != opts[0] {
+					t.Errorf("got %+v, want the whole video option", opt)
+				}
+				return
+			}
+			// the whole video is oversized, the clip is not
+			if opt.Clip.End != tt.wantEnd || opt.Size != tt.wantSize || opt.Oversized || len(opt.ID) == 0 {
+				t.Errorf("got %+v, clip %+v", opt, opt.Clip)
+			}
+			if _, found, _ := repo.Get(opt.ID); !found {
+				t.Error("clip option is not stored")
+			}
+		})
+	}
+}
+
+func TestParseVideoRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      *telebot.Message
+		wantURL  string
+		wantClip *models.Clip
+	}{
+		{
+			name:     "range after url",
+			msg:      &telebot.Message{Text: "https://youtu.be/x 1:23-2:45"},
+			wantURL:  "https://youtu.be/x",
+			wantClip: &models.Clip{Start: 83, End: 165},
+		},
+		{
+			name: "range after scheme-less url",
+			msg: &telebot.Message{
+				Text:     "youtu.be/x 1:23-2:45",
+				Entities: telebot.Entities{{Type: telebot.EntityURL, Offset: 0, Length: 10}},
+			},
+			wantURL:  "https://youtu.be/x",
+			wantClip: &models.Clip{Start: 83, End: 165},
+		},
+		{
+			// shared links often have t=, the whole video is downloaded unless a range is typed
+			name:    "t parameter",
+			msg:     &telebot.Message{Text: "https://youtu.be/x?t=1m23s"},
+			wantURL: "https://youtu.be/x?t=1m23s",
+		},
+		{
+			name:    "no range",
+			msg:     &telebot.Message{Text: "https://youtu.be/x"},
+			wantURL: "https://youtu.be/x",
+		},
+	}
+
+	h := &TelegramMessageHandler{trims: make(map[int64]pendingTrim)}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := h.parseVideoRequests(1, tt.msg)
+			if len(requests) != 1 || requests[0].url != tt.wantURL {
+				t.Fatalf("got %+v", requests)
+			}
+
+			clip := requests[0].clip
+			if (clip == nil) != (tt.wantClip == nil) || clip != nil && *clip != *tt.wantClip {
+				t.Errorf("got clip %+v, want %+v", clip, tt.wantClip)
+			}
+		})
+	}
+}
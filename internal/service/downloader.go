@@ -0,0 +1,20 @@
+package service
+
+import (
+	"context"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+)
+
+// Downloader is a backend which is able to fetch video metadata and media files,
+// e.g. yt-dlp, youtube-dl or a fake used in tests.
+type Downloader interface {
+	// Probe returns raw JSON info dict of the video located by url.
+	Probe(ctx context.Context, url string) ([]byte, error)
+	// Fetch downloads the media described by format to the file at path, onProgress may be nil.
+	Fetch(ctx context.Context, format *models.VideoOption, path string, onProgress ProgressFunc) error
+	// Version returns the backend version, an error means the backend is not usable.
+	Version(ctx context.Context) (string, error)
+}
+
+type ProgressFunc func(p models.Progress)
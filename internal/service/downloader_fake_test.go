@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+)
+
+// fakeDownloader replays canned yt-dlp output and writes files instead of downloading them.
+type fakeDownloader struct {
+	probeOut []byte
+	probeErr error
+	probed   []string
+
+	// fetchFiles are suffixes of files created next to the target path, "" is the target itself
+	fetchFiles []string
+	fetchErr   error
+	fetched    []string
+
+	version      string
+	versionErr   error
+	versionCalls int
+}
+
+func (d *fakeDownloader) Probe(_ context.Context, url string) ([]byte, error) {
+	d.probed = append(d.probed, url)
+
+	return d.probeOut, d.probeErr
+}
+
+func (d *fakeDownloader) Fetch(_ context.Context, _ *models.VideoOption, path string, _ ProgressFunc) error {
+	d.fetched = append(d.fetched, path)
+
+	for _, suffix := range d.fetchFiles {
+		name := path
+		if len(suffix) > 0 {
+			name = strings.TrimSuffix(path, filepath.Ext(path)) + suffix
+		}
+
+		if err := os.WriteFile(name, []byte("media"), 0600); err != nil {
+			return err
+		}
+	}
+
+	return d.fetchErr
+}
+
+func (d *fakeDownloader) Version(_ context.Context) (string, error) {
+	d.versionCalls++
+
+	return d.version, d.versionErr
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/config"
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
+	"github.com/far4599/telegram-bot-youtube-download/internal/repository"
+)
+
+const (
+	// GroupModeMenu replies to links with the download menu
+	GroupModeMenu = "menu"
+	// GroupModeAuto downloads links in the group quality right away
+	GroupModeAuto = "auto"
+	// GroupModeOff ignores links
+	GroupModeOff = "off"
+)
+
+var ErrInvalidGroupSetting = fmt.Errorf("invalid group setting")
+
+type GroupService struct {
+	conf *config.Config
+	repo *repository.GroupRepository
+
+	mu sync.Mutex
+}
+
+func NewGroupService(conf *config.Config, repo *repository.GroupRepository) *GroupService {
+	return &GroupService{
+		conf: conf,
+		repo: repo,
+	}
+}
+
+// Settings returns settings of the group, which are not set by admins are taken from the config.
+func (s *GroupService) Settings(chatID int64) models.GroupSettings {
+	settings := models.GroupSettings{
+		Mode:    s.conf.Group.Mode,
+		Quality: s.conf.Group.Quality,
+	}
+
+	stored, err := s.repo.Get(chatID)
+	if err != nil {
+		log.Logger.Errorw("failed to get group settings", "chat", chatID, "error", err)
+		return settings
+	}
+
+	if len(stored.Mode) > 0 {
+		settings.Mode = stored.Mode
+	}
+	if len(stored.Quality) > 0 {
+		settings.Quality = stored.Quality
+	}
+
+	return settings
+}
+
+// Set changes "mode" or "quality" setting of the group.
+func (s *GroupService) Set(chatID int64, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.repo.Get(chatID)
+	if err != nil {
+		return err
+	}
+
+	switch key {
+	case "mode":
+		if value != GroupModeMenu && value != GroupModeAuto && value != GroupModeOff {
+			return ErrInvalidGroupSetting
+		}
+		stored.Mode = value
+	case "quality":
+		if !ValidQuality(value) {
+			return ErrInvalidGroupSetting
+		}
+		stored.Quality = value
+	default:
+		return ErrInvalidGroupSetting
+	}
+
+	return s.repo.Save(chatID, stored)
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/far4599/telegram-bot-youtube-download/internal/config"
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/far4599/telegram-bot-youtube-download/internal/repository"
+)
+
+type QuotaError struct {
+	Reason  string
+	ResetAt time.Time
+}
+
+func (e *QuotaError) Error() string {
+	if e.ResetAt.IsZero() {
+		return e.Reason
+	}
+
+	return fmt.Sprintf("%s, the limit resets %s", e.Reason, humanize.Time(e.ResetAt))
+}
+
+// LimitService enforces per-user limits, zero limit means unlimited. Admins are not limited.
+type LimitService struct {
+	conf *config.Config
+	repo *repository.UsageRepository
+	qs   *QueueService
+	as   *AccessService
+
+	mu sync.Mutex
+}
+
+func NewLimitService(conf *config.Config, repo *repository.UsageRepository, qs *QueueService, as *AccessService) *LimitService {
+	return &LimitService{
+		conf: conf,
+		repo: repo,
+		qs:   qs,
+		as:   as,
+	}
+}
+
+// Check returns QuotaError if the user may not start new downloads of expected total size.
+func (s *LimitService) Check(userID int64, downloads int, size uint64) error {
+	if s.as.IsAdmin(userID) {
+		return nil
+	}
+
+	limits := s.conf.Limits
+
+	if limits.ConcurrentJobs > 0 && s.activeJobs(userID) >= limits.ConcurrentJobs {
+		return &QuotaError{Reason: fmt.Sprintf("you may have only %d downloads at once, wait until they finish or /cancel them", limits.ConcurrentJobs)}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usage, err := s.usage(userID)
+	if err != nil {
+		return err
+	}
+
+	if limits.DownloadsPerHour > 0 && usage.HourDownloads+downloads > limits.DownloadsPerHour {
+		return &QuotaError{
+			Reason:  fmt.Sprintf("you have reached the limit of %d downloads per hour", limits.DownloadsPerHour),
+			ResetAt: usage.HourStart.Add(time.Hour),
+		}
+	}
+
+	if limits.BytesPerDay > 0 && usage.DayBytes+size > limits.BytesPerDay {
+		return &QuotaError{
+			Reason:  fmt.Sprintf("you have reached the limit of %s per day", humanize.Bytes(limits.BytesPerDay)),
+			ResetAt: usage.DayStart.Add(24 * time.Hour),
+		}
+	}
+
+	return nil
+}
+
+// RemainingDownloads returns the number of downloads the user may start this hour, -1 means unlimited.
+func (s *LimitService) RemainingDownloads(userID int64) (int, error) {
+	limit := s.conf.Limits.DownloadsPerHour
+	if s.as.IsAdmin(userID) || limit == 0 {
+		return -1, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usage, err := s.usage(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if left := limit - usage.HourDownloads; left > 0 {
+		return left, nil
+	}
+
+	return 0, nil
+}
+
+func (s *LimitService) RecordDownloads(userID int64, downloads int) error {
+	return s.update(userID, func(usage *models.Usage) {
+		usage.HourDownloads += downloads
+	})
+}
+
+func (s *LimitService) RecordBytes(userID int64, size uint64) error {
+	return s.update(userID, func(usage *models.Usage) {
+		usage.DayBytes += size
+	})
+}
+
+func (s *LimitService) update(userID int64, fn func(usage *models.Usage)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usage, err := s.usage(userID)
+	if err != nil {
+		return err
+	}
+
+	fn(usage)
+
+	return s.repo.Save(userID, usage)
+}
+
+// usage returns the user usage with expired windows reset.
+func (s *LimitService) usage(userID int64) (*models.Usage, error) {
+	usage, err := s.repo.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if now.Sub(usage.HourStart) >= time.Hour {
+		usage.HourStart = now
+		usage.HourDownloads = 0
+	}
+	if now.Sub(usage.DayStart) >= 24*time.Hour {
+		usage.DayStart = now
+		usage.DayBytes = 0
+	}
+
+	return usage, nil
+}
+
+// activeJobs counts queued and running jobs of the user, a playlist counts as a single job.
+func (s *LimitService) activeJobs(userID int64) int {
+	batches := make(map[string]bool)
+
+	var count int
+	for _, job := range s.qs.UserJobs(userID) {
+		if len(job.BatchID) > 0 {
+			if batches[job.BatchID] {
+				continue
+			}
+			batches[job.BatchID] = true
+		}
+		count++
+	}
+
+	return count
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/config"
+	"github.com/far4599/telegram-bot-youtube-download/internal/repository"
+)
+
+func TestLimitServiceDownloadsPerHour(t *testing.T) {
+	q, _, db := openTestQueue(t, filepath.Join(t.TempDir(), "bot.db"), 1)
+	defer db.Close()
+
+	conf := &config.Config{}
+	conf.Limits.DownloadsPerHour = 3
+	conf.Access.Admins = []int64{100}
+
+	ls := NewLimitService(conf, repository.NewUsageRepository(db), q, NewAccessService(conf, repository.NewAccessRepository(db)))
+
+	for downloads, wantRemaining := range []int{3, 2, 1, 0} {
+		remaining, err := ls.RemainingDownloads(1)
+		if err != nil || remaining != wantRemaining {
+			t.Fatalf("after %d downloads: got %d remaining, error %v", downloads, remaining, err)
+		}
+
+		err = ls.Check(1, 1, 0)
+		if wantRemaining == 0 {
+			var quotaErr *QuotaError
+			if !errors.As(err, &quotaErr) || !strings.Contains(quotaErr.Reason, "limit of 3 downloads per hour") {
+				t.Errorf("got error %v", err)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatalf("after %d downloads: got error %v", downloads, err)
+		}
+
+		if err = ls.RecordDownloads(1, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// admins are not limited
+	if remaining, err := ls.RemainingDownloads(100); err != nil || remaining != -1 {
+		t.Errorf("admin: got %d remaining, error %v", remaining, err)
+	}
+}
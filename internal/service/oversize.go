@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/ffmpeg"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
+	"github.com/google/uuid"
+)
+
+const (
+	OversizeSplit = "split"
+	OversizeLink  = "link"
+
+	// splitSizeRatio leaves room for uneven bitrate, since segments are cut by time
+	splitSizeRatio = 0.9
+)
+
+var ErrFileTooLarge = fmt.Errorf("file is too large")
+
+// IsOversized reports whether the file of the given size can not be uploaded to Telegram.
+func (s *VideoService) IsOversized(size uint64) bool {
+	return size > s.conf.Upload.MaxSize
+}
+
+// SplitFile cuts the downloaded file into parts, which fit upload size limit.
+func (s *VideoService) SplitFile(ctx context.Context, videoOption *models.VideoOption, path string, size uint64) ([]string, error) {
+	duration := videoOption.VideoInfo.Duration
+	if duration <= 0 {
+		return nil, ErrFileTooLarge
+	}
+
+	segmentSeconds := int(float64(duration) * float64(s.conf.Upload.MaxSize) * splitSizeRatio / float64(size))
+	if segmentSeconds < 1 {
+		return nil, ErrFileTooLarge
+	}
+
+	parts, err := ffmpeg.Split(ctx, path, segmentSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, part := range parts {
+		info, err := os.Stat(part)
+		if err == nil && s.IsOversized(uint64(info.Size())) {
+			removeFiles(parts)
+			return nil, ErrFileTooLarge
+		}
+	}
+
+	return parts, nil
+}
+
+// PublishFile moves the downloaded file to the directory served by a web server and returns its public URL.
+// The file gets a random name, so links can not be guessed and jobs of the same option do not collide.
+func (s *VideoService) PublishFile(path string) (string, error) {
+	linkDir, linkURL := s.conf.Upload.LinkDir, s.conf.Upload.LinkURL
+	if len(linkDir) == 0 || len(linkURL) == 0 {
+		return "", ErrFileTooLarge
+	}
+
+	if err := os.MkdirAll(linkDir, 0755); err != nil {
+		return "", err
+	}
+
+	name := uuid.New().String() + filepath.Ext(path)
+	if err := moveFile(path, filepath.Join(linkDir, name)); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(linkURL, "/") + "/" + url.PathEscape(name), nil
+}
+
+// RunLinkCleanup periodically removes published files older than configured TTL until ctx is done.
+func (s *VideoService) RunLinkCleanup(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			entries, err := os.ReadDir(s.conf.Upload.LinkDir)
+			if err != nil {
+				log.Logger.Debugw("failed to read link dir", "error", err)
+				continue
+			}
+
+			for _, entry := range entries {
+				info, err := entry.Info()
+				if err != nil || entry.IsDir() || time.Since(info.ModTime()) < s.conf.Upload.LinkTTL {
+					continue
+				}
+
+				if err = os.Remove(filepath.Join(s.conf.Upload.LinkDir, entry.Name())); err != nil {
+					log.Logger.Errorw("failed to remove published file", "name", entry.Name(), "error", err)
+				}
+			}
+		}
+	}
+}
+
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	// rename does not work across devices, e.g. from tmp to a mounted volume
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+
+	if err = out.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(src)
+}
+
+func removeFiles(paths []string) {
+	for _, path := range paths {
+		_ = os.Remove(path)
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/config"
+)
+
+func TestPublishFile(t *testing.T) {
+	conf := &config.Config{}
+	conf.Upload.LinkDir = t.TempDir()
+	conf.Upload.LinkURL = "https://example.com/downloads/"
+	s := &VideoService{conf: conf}
+
+	links := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		path := filepath.Join(t.TempDir(), "video.mp4")
+		if err := os.WriteFile(path, []byte("media"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		link, err := s.PublishFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(link, "https://example.com/downloads/") || !strings.HasSuffix(link, ".mp4") {
+			t.Errorf("got link %s", link)
+		}
+		links[link] = true
+	}
+
+	// files of the same option do not overwrite each other
+	entries, _ := os.ReadDir(conf.Upload.LinkDir)
+	if len(links) != 2 || len(entries) != 2 {
+		t.Errorf("got links %v, %d published files", links, len(entries))
+	}
+}
+
+func TestPublishFileWithoutLinkDir(t *testing.T) {
+	s := &VideoService{conf: &config.Config{}}
+	if _, err := s.PublishFile("video.mp4"); err != ErrFileTooLarge {
+		t.Errorf("got error %v, want %v", err, ErrFileTooLarge)
+	}
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"strconv"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/google/uuid"
+)
+
+// playlistHeights are the video qualities offered for the whole playlist download.
+var playlistHeights = []int{360, 720}
+
+// GetPlaylistOptions returns options to download all playlist entries as audio or as video of the given quality.
+func (s *VideoService) GetPlaylistOptions(playlist *models.PlaylistInfo) []*models.VideoOption {
+	result := make([]*models.VideoOption, 0, len(playlistHeights)+1)
+
+	opt := &models.VideoOption{
+		FormatID:   "bestaudio[ext=m4a]/bestaudio",
+		Label:      models.AudioCodecMP3.Label,
+		Audio:      true,
+		AudioCodec: models.AudioCodecMP3.Name,
+		Playlist:   playlist,
+	}
+	result = append(result, opt)
+
+	for _, height := range playlistHeights {
+		h := strconv.Itoa(height)
+		opt = &models.VideoOption{
+			FormatID: "best[height<=" + h + "][ext=mp4]/best[height<=" + h + "]",
+			Label:    "p" + h,
+			Playlist: playlist,
+		}
+		result = append(result, opt)
+	}
+	s.saveToCache(result...)
+
+	return result
+}
+
+// PlaylistEntryOptions returns options to download each entry of the playlist chosen with opt,
+// each entry option gets its own ID.
+func PlaylistEntryOptions(opt *models.VideoOption) []models.VideoOption {
+	result := make([]models.VideoOption, 0, len(opt.Playlist.Entries))
+	for _, entry := range opt.Playlist.Entries {
+		result = append(result, models.VideoOption{
+			ID:           uuid.New().String(),
+			FormatID:     opt.FormatID,
+			Label:        opt.Label,
+			Audio:        opt.Audio,
+			AudioCodec:   opt.AudioCodec,
+			CaptionStyle: opt.CaptionStyle,
+			VideoInfo: models.VideoInfo{
+				ID:        entry.ID,
+				Extractor: entry.Extractor,
+				URL:       entry.URL,
+				Title:     entry.Title,
+				Duration:  entry.Duration,
+			},
+		})
+	}
+
+	return result
+}
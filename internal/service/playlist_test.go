@@ -0,0 +1,31 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+)
+
+func TestPlaylistEntryOptions(t *testing.T) {
+	opt := &models.VideoOption{
+		ID:       "playlist",
+		FormatID: "best",
+		Label:    "p720",
+		Playlist: &models.PlaylistInfo{Entries: []models.PlaylistEntry{
+			{ID: "a", URL: "https://youtu.be/a", Title: "A"},
+			{ID: "b", URL: "https://youtu.be/b", Title: "B"},
+		}},
+	}
+
+	ids := make(map[string]bool)
+	for i, entryOpt := range PlaylistEntryOptions(opt) {
+		entry := opt.Playlist.Entries[i]
+		if entryOpt.VideoInfo.URL != entry.URL || entryOpt.FormatID != opt.FormatID || entryOpt.Playlist != nil {
+			t.Errorf("entry %d: got %+v", i, entryOpt)
+		}
+		if entryOpt.ID == opt.ID || ids[entryOpt.ID] {
+			t.Errorf("entry %d: ID %s is not unique", i, entryOpt.ID)
+		}
+		ids[entryOpt.ID] = true
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+)
+
+var qualityRegex = regexp.MustCompile(`^p(\d+)`)
+
+// ValidQuality reports whether the quality is a video height like p720 or an audio codec name like mp3.
+func ValidQuality(quality string) bool {
+	for _, codec := range models.AudioCodecs {
+		if codec.Name == quality {
+			return true
+		}
+	}
+
+	height, ok := qualityHeight(quality)
+
+	return ok && "p"+strconv.Itoa(height) == quality
+}
+
+// DefaultOption selects the audio option of the codec or the best video option not higher than the quality,
+// the lowest video is selected if all of them are higher.
+func DefaultOption(opts []*models.VideoOption, quality string) (*models.VideoOption, bool) {
+	for _, opt := range opts {
+		if opt.Audio && opt.AudioCodec == quality && !opt.SplitChapters {
+			return opt, true
+		}
+	}
+
+	maxHeight, ok := qualityHeight(quality)
+	if !ok {
+		return nil, false
+	}
+
+	var best, lowest *models.VideoOption
+	var bestHeight, lowestHeight int
+	for _, opt := range opts {
+		if opt.Audio || opt.Oversized || opt.Subtitle != nil || len(opt.SubtitleTracks) > 0 {
+			continue
+		}
+
+		height, ok := qualityHeight(opt.Label)
+		if !ok {
+			continue
+		}
+
+		if height <= maxHeight && height > bestHeight {
+			best, bestHeight = opt, height
+		}
+		if lowest == nil || height < lowestHeight {
+			lowest, lowestHeight = opt, height
+		}
+	}
+
+	if best != nil {
+		return best, true
+	}
+
+	return lowest, lowest != nil
+}
+
+func qualityHeight(label string) (int, bool) {
+	match := qualityRegex.FindStringSubmatch(label)
+	if match == nil {
+		return 0, false
+	}
+
+	height, err := strconv.Atoi(match[1])
+
+	return height, err == nil
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/metrics"
+	"github.com/far4599/telegram-bot-youtube-download/internal/repository"
+	"golang.org/x/sync/errgroup"
+)
+
+var ErrJobCancelled = fmt.Errorf("job cancelled")
+
+type JobHandler func(ctx context.Context, job *models.Job) error
+
+type QueueService struct {
+	workers int
+	repo    *repository.JobRepository
+
+	mu      sync.Mutex
+	pending []*models.Job
+	running map[string]*runningJob
+	wakeCh  chan struct{}
+}
+
+type runningJob struct {
+	job    *models.Job
+	cancel context.CancelCauseFunc
+}
+
+func NewQueueService(workers int, repo *repository.JobRepository) (*QueueService, error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs, err := repo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	// jobs interrupted by restart are started from scratch
+	for _, job := range jobs {
+		job.Status = models.JobPending
+	}
+
+	metrics.QueueDepth.WithLabelValues(string(models.JobPending)).Set(float64(len(jobs)))
+
+	return &QueueService{
+		workers: workers,
+		repo:    repo,
+		pending: jobs,
+		running: make(map[string]*runningJob),
+		wakeCh:  make(chan struct{}, workers),
+	}, nil
+}
+
+// Run starts workers, which process jobs with handler, and blocks until ctx is done.
+func (q *QueueService) Run(ctx context.Context, handler JobHandler) error {
+	errGroup, errCtx := errgroup.WithContext(ctx)
+
+	for i := 0; i < q.workers; i++ {
+		errGroup.Go(func() error {
+			q.work(errCtx, handler)
+			return nil
+		})
+	}
+
+	return errGroup.Wait()
+}
+
+// Enqueue persists the job and returns its position in queue, 0 means the job is started immediately.
+func (q *QueueService) Enqueue(job *models.Job) (int, error) {
+	job.Status = models.JobPending
+
+	if err := q.repo.Save(job); err != nil {
+		return 0, err
+	}
+
+	q.mu.Lock()
+	q.pending = append(q.pending, job)
+	position := q.position(len(q.pending) - 1)
+	q.updateMetrics()
+	q.mu.Unlock()
+
+	select {
+	case q.wakeCh <- struct{}{}:
+	default:
+	}
+
+	return position, nil
+}
+
+// Position returns position of the job in queue, 0 means the job is running, -1 means the job is not found.
+func (q *QueueService) Position(jobID string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.running[jobID]; ok {
+		return 0
+	}
+
+	for i, job := range q.pending {
+		if job.ID == jobID {
+			return q.position(i)
+		}
+	}
+
+	return -1
+}
+
+// Job returns running or pending job by its ID.
+func (q *QueueService) Job(jobID string) (*models.Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if rj, ok := q.running[jobID]; ok {
+		return rj.job, true
+	}
+
+	for _, job := range q.pending {
+		if job.ID == jobID {
+			return job, true
+		}
+	}
+
+	return nil, false
+}
+
+// Jobs returns running and pending jobs of all users.
+func (q *QueueService) Jobs() (running, pending []*models.Job) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, rj := range q.running {
+		running = append(running, rj.job)
+	}
+
+	return running, append(pending, q.pending...)
+}
+
+// UserJobs returns running and pending jobs of the user.
+func (q *QueueService) UserJobs(userID int64) []*models.Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var jobs []*models.Job
+	for _, rj := range q.running {
+		if rj.job.UserID == userID {
+			jobs = append(jobs, rj.job)
+		}
+	}
+	for _, job := range q.pending {
+		if job.UserID == userID {
+			jobs = append(jobs, job)
+		}
+	}
+
+	return jobs
+}
+
+// Cancel removes pending job from queue or stops the running one, the context of the running job is cancelled with ErrJobCancelled cause.
+func (q *QueueService) Cancel(jobID string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if rj, ok := q.running[jobID]; ok {
+		rj.cancel(ErrJobCancelled)
+		return true
+	}
+
+	for i, job := range q.pending {
+		if job.ID == jobID {
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			q.deleteJob(job)
+			q.updateMetrics()
+			return true
+		}
+	}
+
+	return false
+}
+
+func (q *QueueService) work(ctx context.Context, handler JobHandler) {
+	for {
+		job, jobCtx, ok := q.next(ctx)
+		if !ok {
+			return
+		}
+
+		err := handler(jobCtx, job)
+		if err != nil {
+			log.Logger.Errorw("job failed", "job", job.ID, "error", err)
+		}
+
+		q.finish(ctx, job, err)
+	}
+}
+
+func (q *QueueService) next(ctx context.Context) (*models.Job, context.Context, bool) {
+	for {
+		// pending jobs are not started on shutdown
+		if ctx.Err() != nil {
+			return nil, nil, false
+		}
+
+		q.mu.Lock()
+		if i := q.nextIndex(); i >= 0 {
+			job := q.pending[i]
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+
+			jobCtx, cancel := context.WithCancelCause(ctx)
+			q.running[job.ID] = &runningJob{
+				job:    job,
+				cancel: cancel,
+			}
+
+			job.Status = models.JobRunning
+			if err := q.repo.Save(job); err != nil {
+				log.Logger.Errorw("failed to save job", "job", job.ID, "error", err)
+			}
+			q.updateMetrics()
+			q.mu.Unlock()
+
+			return job, jobCtx, true
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, false
+		case <-q.wakeCh:
+		}
+	}
+}
+
+// nextIndex returns index of the first pending job, which batch has no running jobs, or -1.
+func (q *QueueService) nextIndex() int {
+	runningBatches := make(map[string]bool, len(q.running))
+	for _, rj := range q.running {
+		if len(rj.job.BatchID) > 0 {
+			runningBatches[rj.job.BatchID] = true
+		}
+	}
+
+	for i, job := range q.pending {
+		if !runningBatches[job.BatchID] {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// finish removes the job, err is the handler result. A job interrupted by shutdown is kept to resume it after restart.
+func (q *QueueService) finish(ctx context.Context, job *models.Job, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if rj, ok := q.running[job.ID]; ok {
+		rj.cancel(nil)
+		delete(q.running, job.ID)
+	}
+	q.updateMetrics()
+
+	// the job may be finished right before shutdown
+	if ctx.Err() != nil && errors.Is(err, context.Canceled) {
+		return
+	}
+
+	q.deleteJob(job)
+}
+
+func (q *QueueService) deleteJob(job *models.Job) {
+	if err := q.repo.Delete(job.ID); err != nil {
+		log.Logger.Errorw("failed to delete job", "job", job.ID, "error", err)
+	}
+}
+
+// updateMetrics must be called with q.mu locked.
+func (q *QueueService) updateMetrics() {
+	metrics.QueueDepth.WithLabelValues(string(models.JobPending)).Set(float64(len(q.pending)))
+	metrics.QueueDepth.WithLabelValues(string(models.JobRunning)).Set(float64(len(q.running)))
+}
+
+func (q *QueueService) position(pendingIndex int) int {
+	position := pendingIndex + 1 + len(q.running) - q.workers
+	if position < 0 {
+		return 0
+	}
+
+	return position
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/far4599/telegram-bot-youtube-download/internal/repository"
+)
+
+func openTestQueue(t *testing.T, path string, workers int) (*QueueService, *repository.JobRepository, *repository.BoltDB) {
+	t.Helper()
+
+	db, err := repository.NewBoltDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := repository.NewJobRepository(db)
+	q, err := NewQueueService(workers, repo)
+	if err != nil {
+		db.Close()
+		t.Fatal(err)
+	}
+
+	return q, repo, db
+}
+
+func enqueueTestJobs(t *testing.T, q *QueueService, ids ...string) {
+	t.Helper()
+
+	createdAt := time.Now()
+	for i, id := range ids {
+		job := &models.Job{ID: id, UserID: 1, CreatedAt: createdAt.Add(time.Duration(i) * time.Millisecond)}
+		if _, err := q.Enqueue(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func persistedJobIDs(t *testing.T, repo *repository.JobRepository) []string {
+	t.Helper()
+
+	jobs, err := repo.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		ids = append(ids, job.ID)
+	}
+
+	return ids
+}
+
+// waitJobRemoved waits until the handler of the job returns.
+func waitJobRemoved(t *testing.T, q *QueueService, jobID string) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); q.Position(jobID) != -1; {
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s is still in queue", jobID)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func equalIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestQueueFIFO(t *testing.T) {
+	q, repo, db := openTestQueue(t, filepath.Join(t.TempDir(), "bot.db"), 1)
+	defer db.Close()
+
+	enqueueTestJobs(t, q, "a", "b", "c")
+
+	for want, id := range []string{"a", "b", "c"} {
+		if got := q.Position(id); got != want {
+			t.Errorf("position of %s: got %d, want %d", id, got, want)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan string)
+	proceed := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- q.Run(ctx, func(_ context.Context, job *models.Job) error {
+			started <- job.ID
+			<-proceed
+			return nil
+		})
+	}()
+
+	var order []string
+	for i := 0; i < 3; i++ {
+		id := <-started
+		order = append(order, id)
+
+		if got := q.Position(id); got != 0 {
+			t.Errorf("position of running %s: got %d, want 0", id, got)
+		}
+		proceed <- struct{}{}
+	}
+
+	if !equalIDs(order, []string{"a", "b", "c"}) {
+		t.Errorf("got order %v", order)
+	}
+
+	waitJobRemoved(t, q, "c")
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := persistedJobIDs(t, repo); len(ids) != 0 {
+		t.Errorf("finished jobs are persisted: %v", ids)
+	}
+}
+
+func TestQueueCancelPending(t *testing.T) {
+	q, repo, db := openTestQueue(t, filepath.Join(t.TempDir(), "bot.db"), 1)
+	defer db.Close()
+
+	enqueueTestJobs(t, q, "a", "b", "c")
+
+	if !q.Cancel("b") {
+		t.Fatal("pending job is not cancelled")
+	}
+	if q.Cancel("b") {
+		t.Error("job is cancelled twice")
+	}
+
+	if got := q.Position("b"); got != -1 {
+		t.Errorf("position of cancelled job: got %d, want -1", got)
+	}
+	if got := q.Position("c"); got != 1 {
+		t.Errorf("position of next job: got %d, want 1", got)
+	}
+	if ids := persistedJobIDs(t, repo); !equalIDs(ids, []string{"a", "c"}) {
+		t.Errorf("got persisted jobs %v", ids)
+	}
+}
+
+func TestQueueCancelRunning(t *testing.T) {
+	q, repo, db := openTestQueue(t, filepath.Join(t.TempDir(), "bot.db"), 1)
+	defer db.Close()
+
+	enqueueTestJobs(t, q, "a")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan struct{})
+	cause := make(chan error, 1)
+	go q.Run(ctx, func(jobCtx context.Context, _ *models.Job) error {
+		close(started)
+		<-jobCtx.Done()
+		cause <- context.Cause(jobCtx)
+		return nil
+	})
+
+	<-started
+	if !q.Cancel("a") {
+		t.Fatal("running job is not cancelled")
+	}
+
+	select {
+	case err := <-cause:
+		if err != ErrJobCancelled {
+			t.Errorf("got cause %v, want %v", err, ErrJobCancelled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("running job is not stopped")
+	}
+
+	waitJobRemoved(t, q, "a")
+	if ids := persistedJobIDs(t, repo); len(ids) != 0 {
+		t.Errorf("cancelled job is persisted: %v", ids)
+	}
+}
+
+func TestQueueReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bot.db")
+
+	q, _, db := openTestQueue(t, path, 1)
+	enqueueTestJobs(t, q, "a", "b", "c")
+
+	// the running job is interrupted by shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- q.Run(ctx, func(jobCtx context.Context, _ *models.Job) error {
+			cancel()
+			<-jobCtx.Done()
+			return jobCtx.Err()
+		})
+	}()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	q, _, db = openTestQueue(t, path, 1)
+	defer db.Close()
+
+	_, pending := q.Jobs()
+
+	var ids []string
+	for _, job := range pending {
+		ids = append(ids, job.ID)
+		if job.Status != models.JobPending {
+			t.Errorf("job %s: got status %s, want %s", job.ID, job.Status, models.JobPending)
+		}
+	}
+	if !equalIDs(ids, []string{"a", "b", "c"}) {
+		t.Errorf("got jobs %v after reopen", ids)
+	}
+}
+
+func TestQueueFinishedBeforeShutdown(t *testing.T) {
+	q, repo, db := openTestQueue(t, filepath.Join(t.TempDir(), "bot.db"), 1)
+	defer db.Close()
+
+	enqueueTestJobs(t, q, "a", "b")
+
+	// the job succeeds after shutdown is started, the next one is not started
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := q.Run(ctx, func(context.Context, *models.Job) error {
+		cancel()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := persistedJobIDs(t, repo); !equalIDs(ids, []string{"b"}) {
+		t.Errorf("got persisted jobs %v, want only the pending one", ids)
+	}
+}
+
+func TestQueueNotStartedOnShutdown(t *testing.T) {
+	q, _, db := openTestQueue(t, filepath.Join(t.TempDir(), "bot.db"), 1)
+	defer db.Close()
+
+	enqueueTestJobs(t, q, "a", "b")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var handled int
+	if err := q.Run(ctx, func(context.Context, *models.Job) error {
+		handled++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if handled != 0 {
+		t.Errorf("%d jobs started after shutdown", handled)
+	}
+}
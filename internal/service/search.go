@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/google/uuid"
+)
+
+// searchHeight is the video quality of search results, since formats are unknown until the video is probed.
+const searchHeight = "720"
+
+// Search returns first limit videos found by the query. The search provider is a yt-dlp pseudo URL prefix,
+// e.g. "ytsearch5:query" returns first 5 YouTube results as a playlist.
+func (s *VideoService) Search(ctx context.Context, query string, limit int) (*models.PlaylistInfo, error) {
+	_, playlist, err := s.GetVideoInfo(ctx, fmt.Sprintf("%s%d:%s", s.conf.Search.Provider, limit, query))
+	if err != nil {
+		return nil, err
+	}
+
+	if playlist == nil {
+		return nil, ErrVideoNotFound
+	}
+
+	return playlist, nil
+}
+
+// SearchVideos searches the query and keeps the results for pagination.
+func (s *VideoService) SearchVideos(ctx context.Context, query string) (*models.SearchResult, error) {
+	playlist, err := s.Search(ctx, query, s.conf.Search.Results)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.SearchResult{
+		ID:      uuid.New().String(),
+		Query:   query,
+		Entries: playlist.Entries,
+	}
+	s.searches.Put(result)
+
+	return result, nil
+}
+
+func (s *VideoService) GetSearchResult(id string) (*models.SearchResult, bool) {
+	return s.searches.Get(id)
+}
+
+// GetSearchOptions returns an option per playlist entry to download the video of default quality.
+func (s *VideoService) GetSearchOptions(playlist *models.PlaylistInfo) []*models.VideoOption {
+	result := make([]*models.VideoOption, 0, len(playlist.Entries))
+	for _, entry := range playlist.Entries {
+		opt := &models.VideoOption{
+			FormatID: "best[height<=" + searchHeight + "][ext=mp4]/best[height<=" + searchHeight + "]",
+			Label:    "p" + searchHeight,
+			VideoInfo: models.VideoInfo{
+				ID:        entry.ID,
+				Extractor: entry.Extractor,
+				URL:       entry.URL,
+				Title:     entry.Title,
+				ThumbURL:  entry.ThumbURL,
+				Duration:  entry.Duration,
+			},
+		}
+
+		result = append(result, opt)
+	}
+	s.saveToCache(result...)
+
+	return result
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
+	"github.com/far4599/telegram-bot-youtube-download/internal/repository"
+)
+
+const (
+	SettingQuality  = "quality"
+	SettingCodec    = "codec"
+	SettingAuto     = "auto"
+	SettingSubtitle = "subs"
+	SettingCaption  = "caption"
+
+	settingOn  = "on"
+	settingOff = "off"
+)
+
+var (
+	ErrInvalidSetting = fmt.Errorf("invalid setting")
+
+	// SettingKeys are listed in the settings menu in this order
+	SettingKeys = []string{SettingQuality, SettingCodec, SettingAuto, SettingSubtitle, SettingCaption}
+
+	// settingValues are cycled by the settings menu buttons
+	settingValues = map[string][]string{
+		SettingQuality:  {"p360", "p720", "p1080", "p2160", models.QualityAudio},
+		SettingCodec:    {models.AudioCodecMP3.Name, models.AudioCodecOpus.Name, models.AudioCodecM4A.Name},
+		SettingAuto:     {settingOff, settingOn},
+		SettingSubtitle: {settingOff, "en", "es", "de", "fr", "ru"},
+		SettingCaption:  {models.CaptionFull, models.CaptionTitle, models.CaptionNone},
+	}
+
+	defaultSettings = models.UserSettings{
+		Quality:      "p720",
+		AudioCodec:   models.AudioCodecMP3.Name,
+		CaptionStyle: models.CaptionFull,
+	}
+)
+
+type SettingsService struct {
+	repo *repository.SettingsRepository
+
+	mu sync.Mutex
+}
+
+func NewSettingsService(repo *repository.SettingsRepository) *SettingsService {
+	return &SettingsService{
+		repo: repo,
+	}
+}
+
+// Get returns settings of the user, defaults are returned if the user has not changed them.
+func (s *SettingsService) Get(userID int64) models.UserSettings {
+	settings, found, err := s.repo.Get(userID)
+	if err != nil {
+		log.Logger.Errorw("failed to get user settings", "user", userID, "error", err)
+		return defaultSettings
+	}
+	if !found {
+		return defaultSettings
+	}
+
+	return *settings
+}
+
+// Set changes the setting of the user, see SettingKeys.
+func (s *SettingsService) Set(userID int64, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings := s.Get(userID)
+	if err := setSettingValue(&settings, key, value); err != nil {
+		return err
+	}
+
+	return s.repo.Save(userID, &settings)
+}
+
+// Next switches the setting to its next value.
+func (s *SettingsService) Next(userID int64, key string) error {
+	values, ok := settingValues[key]
+	if !ok {
+		return ErrInvalidSetting
+	}
+
+	current := SettingValue(s.Get(userID), key)
+
+	next := values[0]
+	for i, value := range values {
+		if value == current && i+1 < len(values) {
+			next = values[i+1]
+		}
+	}
+
+	return s.Set(userID, key, next)
+}
+
+// SettingValue returns the setting as it is shown to the user.
+func SettingValue(settings models.UserSettings, key string) string {
+	switch key {
+	case SettingQuality:
+		return settings.Quality
+	case SettingCodec:
+		return settings.AudioCodec
+	case SettingAuto:
+		if settings.AutoDownload {
+			return settingOn
+		}
+		return settingOff
+	case SettingSubtitle:
+		if len(settings.SubtitleLang) == 0 {
+			return settingOff
+		}
+		return settings.SubtitleLang
+	case SettingCaption:
+		return settings.CaptionStyle
+	}
+
+	return ""
+}
+
+// DefaultQuality returns the quality for DefaultOption, audio quality is the preferred codec.
+func DefaultQuality(settings models.UserSettings) string {
+	if settings.Quality == models.QualityAudio {
+		return settings.AudioCodec
+	}
+
+	return settings.Quality
+}
+
+func offeredSettingValue(key, value string) bool {
+	for _, v := range settingValues[key] {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func setSettingValue(settings *models.UserSettings, key, value string) error {
+	switch key {
+	case SettingQuality:
+		if value != models.QualityAudio && !ValidQuality(value) {
+			return ErrInvalidSetting
+		}
+		settings.Quality = value
+	case SettingCodec:
+		if models.AudioCodecByName(value).Name != value {
+			return ErrInvalidSetting
+		}
+		settings.AudioCodec = value
+	case SettingAuto:
+		if value != settingOn && value != settingOff {
+			return ErrInvalidSetting
+		}
+		settings.AutoDownload = value == settingOn
+	case SettingSubtitle:
+		// the language is passed to yt-dlp
+		if !offeredSettingValue(key, value) {
+			return ErrInvalidSetting
+		}
+		if value == settingOff {
+			value = ""
+		}
+		settings.SubtitleLang = value
+	case SettingCaption:
+		if value != models.CaptionFull && value != models.CaptionTitle && value != models.CaptionNone {
+			return ErrInvalidSetting
+		}
+		settings.CaptionStyle = value
+	default:
+		return ErrInvalidSetting
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+)
+
+func TestSetSettingValue(t *testing.T) {
+	tests := []struct {
+		key     string
+		value   string
+		wantErr bool
+	}{
+		{key: SettingQuality, value: "p1080"},
+		{key: SettingQuality, value: "hd", wantErr: true},
+		{key: SettingCodec, value: models.AudioCodecOpus.Name},
+		{key: SettingCodec, value: "flac", wantErr: true},
+		{key: SettingAuto, value: settingOn},
+		{key: SettingSubtitle, value: "de"},
+		{key: SettingSubtitle, value: settingOff},
+		{key: SettingSubtitle, value: "pt", wantErr: true},
+		{key: SettingSubtitle, value: "en,--exec", wantErr: true},
+		{key: SettingCaption, value: models.CaptionTitle},
+		{key: "unknown", value: "x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			settings := defaultSettings
+
+			err := setSettingValue(&settings, tt.key, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v", err)
+			}
+			if err != nil {
+				if settings != defaultSettings {
+					t.Errorf("settings are changed by invalid value: %+v", settings)
+				}
+				return
+			}
+
+			if got := SettingValue(settings, tt.key); got != tt.value {
+				t.Errorf("got %q, want %q", got, tt.value)
+			}
+		})
+	}
+}
+
+func TestApplyUserSettings(t *testing.T) {
+	settings := defaultSettings
+	settings.AudioCodec = models.AudioCodecOpus.Name
+	settings.CaptionStyle = models.CaptionNone
+
+	opt := &models.VideoOption{
+		Label:      models.AudioCodecMP3.Label,
+		Audio:      true,
+		AudioCodec: models.AudioCodecMP3.Name,
+		Playlist:   &models.PlaylistInfo{Entries: []models.PlaylistEntry{{URL: "https://youtu.be/a"}}},
+	}
+
+	entries := PlaylistEntryOptions(applyUserSettings(settings, opt))
+	if got := entries[0]; got.AudioCodec != models.AudioCodecOpus.Name || got.CaptionStyle != models.CaptionNone {
+		t.Errorf("got entry %+v", got)
+	}
+	if opt.AudioCodec != models.AudioCodecMP3.Name || len(opt.CaptionStyle) > 0 {
+		t.Errorf("cached option is changed: %+v", opt)
+	}
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/metrics"
+	"github.com/far4599/telegram-bot-youtube-download/internal/repository"
+	"github.com/pkg/errors"
+)
+
+const (
+	ActivityDownload = "downloading"
+	ActivityUpload   = "uploading"
+
+	statsDateLayout  = "2006-01-02"
+	unknownExtractor = "unknown"
+)
+
+// StatsService counts downloads and uploads per day and extractor, and tracks the ones in progress.
+type StatsService struct {
+	repo *repository.StatsRepository
+
+	mu     sync.Mutex
+	active map[string]*models.Activity
+
+	// usernames of users saved since start
+	usersMu sync.Mutex
+	users   map[int64]string
+}
+
+func NewStatsService(repo *repository.StatsRepository) *StatsService {
+	return &StatsService{
+		repo:   repo,
+		active: make(map[string]*models.Activity),
+		users:  make(map[int64]string),
+	}
+}
+
+// Begin marks the job as being in the stage, the returned func records the result and must be called when it is done.
+func (s *StatsService) Begin(job *models.Job, stage string) func(size uint64, err error) {
+	startedAt := time.Now()
+
+	s.mu.Lock()
+	s.active[job.ID] = &models.Activity{
+		Job:       job,
+		Stage:     stage,
+		StartedAt: startedAt,
+	}
+	s.mu.Unlock()
+
+	return func(size uint64, err error) {
+		s.mu.Lock()
+		delete(s.active, job.ID)
+		s.mu.Unlock()
+
+		// cancelled jobs are not errors
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+
+		extractor := job.VideoOption.VideoInfo.Extractor
+		if len(extractor) == 0 {
+			extractor = unknownExtractor
+		}
+
+		metrics.TransferDuration.WithLabelValues(stage, extractor, metrics.Result(err)).Observe(time.Since(startedAt).Seconds())
+		if err == nil {
+			metrics.TransferBytes.WithLabelValues(stage, extractor).Observe(float64(size))
+		}
+
+		if errR := s.record(extractor, stage, size, err); errR != nil {
+			log.Logger.Errorw("failed to record stats", "job", job.ID, "error", errR)
+		}
+	}
+}
+
+// Active returns downloads and uploads in progress, the oldest first.
+func (s *StatsService) Active() []models.Activity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	activities := make([]models.Activity, 0, len(s.active))
+	for _, activity := range s.active {
+		activities = append(activities, *activity)
+	}
+
+	sort.Slice(activities, func(i, j int) bool {
+		return activities[i].StartedAt.Before(activities[j].StartedAt)
+	})
+
+	return activities
+}
+
+// Days returns stats of the last days, today first.
+func (s *StatsService) Days(days int) ([]*models.DailyStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	result := make([]*models.DailyStats, 0, days)
+	for i := 0; i < days; i++ {
+		stats, err := s.repo.Get(now.AddDate(0, 0, -i).Format(statsDateLayout))
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, stats)
+	}
+
+	return result, nil
+}
+
+// RecordUser remembers the user, so broadcasts reach everyone who has used the bot. The username is kept
+// to check access rules by username.
+func (s *StatsService) RecordUser(userID int64, username string) {
+	s.usersMu.Lock()
+	defer s.usersMu.Unlock()
+
+	if saved, ok := s.users[userID]; ok && saved == username {
+		return
+	}
+
+	if err := s.repo.SaveUser(userID, username); err != nil {
+		log.Logger.Errorw("failed to save user", "user", userID, "error", err)
+		return
+	}
+
+	s.users[userID] = username
+}
+
+func (s *StatsService) Users() ([]*models.KnownUser, error) {
+	return s.repo.Users()
+}
+
+func (s *StatsService) record(extractor, stage string, size uint64, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, errG := s.repo.Get(time.Now().Format(statsDateLayout))
+	if errG != nil {
+		return errG
+	}
+
+	es, ok := stats.Extractors[extractor]
+	if !ok {
+		es = new(models.ExtractorStats)
+		stats.Extractors[extractor] = es
+	}
+
+	switch {
+	case stage == ActivityDownload && err != nil:
+		es.DownloadErrors++
+	case stage == ActivityDownload:
+		es.Downloads++
+		es.DownloadBytes += size
+	case err != nil:
+		es.UploadErrors++
+	default:
+		es.Uploads++
+		es.UploadBytes += size
+	}
+
+	return s.repo.Save(stats)
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
+	"gopkg.in/telebot.v3"
+)
+
+// statusEditInterval keeps message edits well below Telegram limits.
+const statusEditInterval = 3 * time.Second
+
+// statusMessage is a message edited in place to report the job state.
+type statusMessage struct {
+	bot    *telebot.Bot
+	msg    telebot.Editable
+	markup *telebot.ReplyMarkup
+	prefix string
+
+	editMu sync.Mutex // serializes edits, so the latest text wins
+
+	mu       sync.Mutex
+	lastEdit time.Time
+	lastText string
+	editing  bool
+}
+
+func newStatusMessage(bot *telebot.Bot, chatID int64, messageID int, markup *telebot.ReplyMarkup) *statusMessage {
+	return &statusMessage{
+		bot: bot,
+		msg: telebot.StoredMessage{
+			MessageID: strconv.Itoa(messageID),
+			ChatID:    chatID,
+		},
+		markup: markup,
+	}
+}
+
+// jobStatusMessage returns the status message of the job, jobs started in inline mode report the status in the inline message.
+func jobStatusMessage(bot *telebot.Bot, job *models.Job, markup *telebot.ReplyMarkup) *statusMessage {
+	if len(job.InlineMessageID) > 0 {
+		return &statusMessage{
+			bot:    bot,
+			msg:    telebot.StoredMessage{MessageID: job.InlineMessageID},
+			markup: markup,
+		}
+	}
+
+	return newStatusMessage(bot, job.ChatID, job.StatusMessageID, markup)
+}
+
+// Update edits the message unless it was edited recently or the previous edit is still in progress.
+func (s *statusMessage) Update(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.editing || text == s.lastText || time.Since(s.lastEdit) < statusEditInterval {
+		return
+	}
+
+	s.editing = true
+	s.lastText = text
+	s.lastEdit = time.Now()
+
+	go func() {
+		s.editMu.Lock()
+		defer s.editMu.Unlock()
+
+		s.mu.Lock()
+		stale := s.lastText != text
+		s.mu.Unlock()
+
+		if !stale {
+			s.edit(text, s.markup)
+		}
+
+		s.mu.Lock()
+		s.editing = false
+		s.mu.Unlock()
+	}()
+}
+
+// Set edits the message immediately.
+func (s *statusMessage) Set(text string) {
+	s.set(text, s.markup)
+}
+
+// Finish edits the message immediately and removes its buttons.
+func (s *statusMessage) Finish(text string) {
+	s.set(text, nil)
+}
+
+func (s *statusMessage) Delete() {
+	if err := s.bot.Delete(s.msg); err != nil {
+		log.Logger.Debugw("failed to delete status message", "error", err)
+	}
+}
+
+func (s *statusMessage) DownloadProgress(p models.Progress) {
+	text := "downloading"
+	if p.Total > 0 {
+		text += fmt.Sprintf(": %.1f%% of %s", p.Percent(), humanize.Bytes(p.Total))
+	}
+	if p.Speed > 0 {
+		text += fmt.Sprintf(", %s/s", humanize.Bytes(uint64(p.Speed)))
+	}
+	if p.ETA > 0 {
+		text += fmt.Sprintf(", ETA %s", p.ETA)
+	}
+
+	s.Update(text)
+}
+
+func (s *statusMessage) UploadProgress(percent int32) {
+	s.Update(fmt.Sprintf("uploading: %d%%", percent))
+}
+
+func (s *statusMessage) set(text string, markup *telebot.ReplyMarkup) {
+	s.mu.Lock()
+	s.lastText = text
+	s.lastEdit = time.Now()
+	s.mu.Unlock()
+
+	s.editMu.Lock()
+	defer s.editMu.Unlock()
+
+	s.edit(text, markup)
+}
+
+func (s *statusMessage) edit(text string, markup *telebot.ReplyMarkup) {
+	var opts []any
+	if markup != nil {
+		opts = append(opts, markup)
+	}
+
+	if _, err := s.bot.Edit(s.msg, s.prefix+text, opts...); err != nil {
+		log.Logger.Debugw("failed to edit status message", "error", err)
+	}
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/ffmpeg"
+)
+
+var ErrSubtitlesNotFound = fmt.Errorf("subtitles not found")
+
+// maxSubtitleTracks limits the languages menu, youtube offers auto captions translated to a hundred languages.
+const maxSubtitleTracks = 20
+
+// GetSubtitlesOption returns an option, which opens subtitle languages menu. The subtitles are embedded
+// into the best video option, which does not exceed upload limit.
+func (s *VideoService) GetSubtitlesOption(videoInfo *models.VideoInfo, opts []*models.VideoOption) (*models.VideoOption, bool) {
+	tracks := subtitleTracks(videoInfo)
+	if len(tracks) == 0 {
+		return nil, false
+	}
+
+	var base *models.VideoOption
+	for _, opt := range opts {
+		// options with subtitles already chosen are not a base of the menu
+		if !opt.Audio && !opt.Oversized && opt.Subtitle == nil {
+			base = opt
+		}
+	}
+	if base == nil {
+		return nil, false
+	}
+
+	opt := *base
+	opt.SubtitleTracks = tracks
+	s.saveToCache(&opt)
+
+	return &opt, true
+}
+
+// GetSubtitleTrackOptions returns an option per subtitle language of the subtitles option.
+func (s *VideoService) GetSubtitleTrackOptions(subtitlesOpt *models.VideoOption) []*models.VideoOption {
+	result := make([]*models.VideoOption, 0, len(subtitlesOpt.SubtitleTracks))
+	for i := range subtitlesOpt.SubtitleTracks {
+		opt := *subtitlesOpt
+		opt.SubtitleTracks = nil
+		opt.Subtitle = &subtitlesOpt.SubtitleTracks[i]
+
+		result = append(result, &opt)
+	}
+	s.saveToCache(result...)
+
+	return result
+}
+
+// GetSubtitleModeOptions returns an option per delivery mode of the chosen subtitle track.
+func (s *VideoService) GetSubtitleModeOptions(trackOpt *models.VideoOption) []*models.VideoOption {
+	result := make([]*models.VideoOption, 0, len(models.SubtitleModes))
+	for _, mode := range models.SubtitleModes {
+		opt := *trackOpt
+		opt.SubtitleMode = mode.Name
+
+		if mode.File() {
+			opt.Label = trackOpt.Subtitle.Lang + " subtitles"
+			opt.Size = 0
+			opt.Oversized = false
+		} else {
+			opt.Label = fmt.Sprintf("%s + %s subtitles", trackOpt.Label, trackOpt.Subtitle.Lang)
+		}
+
+		result = append(result, &opt)
+	}
+	s.saveToCache(result...)
+
+	return result
+}
+
+// GetPreferredSubtitleOption returns a copy of the video option with subtitles of the language embedded,
+// if the video has them.
+func (s *VideoService) GetPreferredSubtitleOption(videoInfo *models.VideoInfo, videoOpt *models.VideoOption, lang string) (*models.VideoOption, bool) {
+	for _, track := range subtitleTracks(videoInfo) {
+		if track.Lang != lang && strings.TrimSuffix(track.Lang, "-orig") != lang {
+			continue
+		}
+
+		opt := *videoOpt
+		opt.Subtitle = &track
+		opt.SubtitleMode = models.SubtitleModeSoft.Name
+		opt.Label = fmt.Sprintf("%s + %s subtitles", videoOpt.Label, track.Lang)
+		s.saveToCache(&opt)
+
+		return &opt, true
+	}
+
+	return nil, false
+}
+
+// applySubtitles finds subtitles downloaded along with the video and returns path of the file to deliver.
+func (s *VideoService) applySubtitles(ctx context.Context, opt *models.VideoOption, videoPath string) (string, error) {
+	mode := models.SubtitleModeByName(opt.SubtitleMode)
+	if mode == models.SubtitleModeSoft {
+		// embedded by yt-dlp
+		return videoPath, nil
+	}
+
+	ext := mode.Ext
+	if !mode.File() {
+		ext = models.SubtitleModeSRT.Ext
+	}
+
+	basePath := strings.TrimSuffix(videoPath, filepath.Ext(videoPath))
+
+	subs, err := filepath.Glob(basePath + ".*." + ext)
+	if err != nil {
+		return "", err
+	}
+	if len(subs) == 0 {
+		return "", ErrSubtitlesNotFound
+	}
+
+	if mode.File() {
+		return subs[0], nil
+	}
+
+	outPath := basePath + "_subs" + filepath.Ext(videoPath)
+	err = ffmpeg.BurnSubtitles(ctx, videoPath, subs[0], outPath)
+	removeFiles(append(subs, videoPath))
+	if err != nil {
+		return "", err
+	}
+
+	return outPath, nil
+}
+
+// subtitleTracks lists uploaded subtitles first and then auto captions of the original language,
+// translated auto captions are listed only if there is nothing else.
+func subtitleTracks(videoInfo *models.VideoInfo) []models.SubtitleTrack {
+	var result []models.SubtitleTrack
+
+	for _, lang := range sortedLangs(videoInfo.Subtitles) {
+		// chat replay of youtube streams
+		if lang == "live_chat" {
+			continue
+		}
+
+		result = append(result, models.SubtitleTrack{
+			Lang: lang,
+			Name: videoInfo.Subtitles[lang][0].Name,
+		})
+	}
+
+	var orig, translated []models.SubtitleTrack
+	for _, lang := range sortedLangs(videoInfo.AutomaticCaptions) {
+		track := models.SubtitleTrack{
+			Lang: lang,
+			Name: videoInfo.AutomaticCaptions[lang][0].Name,
+			Auto: true,
+		}
+
+		if strings.HasSuffix(lang, "-orig") {
+			orig = append(orig, track)
+		} else {
+			translated = append(translated, track)
+		}
+	}
+
+	result = append(result, orig...)
+	if len(result) == 0 {
+		result = translated
+	}
+
+	if len(result) > maxSubtitleTracks {
+		result = result[:maxSubtitleTracks]
+	}
+
+	return result
+}
+
+func sortedLangs(subtitles map[string][]models.Subtitle) []string {
+	langs := make([]string, 0, len(subtitles))
+	for lang, tracks := range subtitles {
+		if len(tracks) > 0 {
+			langs = append(langs, lang)
+		}
+	}
+	sort.Strings(langs)
+
+	return langs
+}
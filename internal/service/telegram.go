@@ -7,6 +7,7 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dustin/go-humanize"
@@ -14,31 +15,62 @@ import (
 	"github.com/far4599/telegram-bot-youtube-download/internal/models"
 	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
 	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/telegram"
-	"github.com/gotd/td/tg"
+	"github.com/google/uuid"
 	"gopkg.in/telebot.v3"
 )
 
 const (
 	videoEmoji = "🎥"
 	audioEmoji = "🎧"
+
+	subtitlesEmoji = "💬"
+
+	oversizedEmoji = "⚠️"
 )
 
+var urlRegex = regexp.MustCompile(`https?://[^\s"]+`)
+
+var CancelJobButton = telebot.Btn{Unique: "cancel_job"}
+
 type TelegramMessageHandler struct {
 	conf *config.Config
 
 	vs *VideoService
+	qs *QueueService
+	as *AccessService
+	ls *LimitService
+	gs *GroupService
+	ss *SettingsService
+	st *StatsService
+
+	trimMu sync.Mutex
+	trims  map[int64]pendingTrim
+
+	inlineMu      sync.Mutex
+	inlineQueries map[int64]*inlineQuery
+	inlineSem     chan struct{}
 }
 
-func NewMessageHandler(conf *config.Config, vs *VideoService) *TelegramMessageHandler {
+func NewMessageHandler(conf *config.Config, vs *VideoService, qs *QueueService, as *AccessService, ls *LimitService, gs *GroupService, ss *SettingsService, st *StatsService) *TelegramMessageHandler {
 	return &TelegramMessageHandler{
-		conf: conf,
-		vs:   vs,
+		conf:  conf,
+		vs:    vs,
+		qs:    qs,
+		as:    as,
+		ls:    ls,
+		gs:    gs,
+		ss:    ss,
+		st:    st,
+		trims: make(map[int64]pendingTrim),
+
+		inlineQueries: make(map[int64]*inlineQuery),
+		inlineSem:     make(chan struct{}, maxInlineQueries),
 	}
 }
 
 func (h *TelegramMessageHandler) OnStart() telebot.HandlerFunc {
 	return func(m telebot.Context) (err error) {
-		m.Send("Send me a video link (YouTube, Vimeo, etc). You may also share a link from the streaming application. And I'll send you download options if streaming service is supported.")
+		m.Send("Send me a video link (YouTube, Vimeo, etc). You may also share a link from the streaming application. And I'll send you download options if streaming service is supported. Or just send me a text to search videos. Use /settings to change your preferences.\n\nAdd a time range after the link to download only a part of the video, e.g. 'https://youtu.be/... 1:23-2:45'.")
 
 		return nil
 	}
@@ -48,58 +80,397 @@ func (h *TelegramMessageHandler) OnCallback(userbotClient *telegram.UserBotClien
 	return func(m telebot.Context) (err error) {
 		defer func() {
 			if err != nil {
-				errMsg := "error on upload: '%s'"
-				if errors.Is(err, new(dlpError)) {
-					errMsg = "error on download: '%s'"
-				}
-				defer m.Bot().Send(m.Sender(), fmt.Sprintf(errMsg, err))
+				defer m.Bot().Send(m.Sender(), errorText(err))
 			}
 		}()
 
 		defer m.Respond()
 
-		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Hour)
-		defer cancel()
-
 		videoID := strings.TrimSpace(m.Callback().Data)
 		videoOption, ok := h.vs.getFromCache(videoID)
 		if !ok {
 			return ErrNotFound
 		}
 
-		if videoOption.Audio {
-			_ = m.Notify(telebot.UploadingDocument)
-		} else {
-			_ = m.Notify(telebot.UploadingVideo)
+		if len(videoOption.SubtitleTracks) > 0 {
+			msg, opts := createSubtitleTracksMessage(videoOption, h.vs.GetSubtitleTrackOptions(videoOption))
+			return m.Send(msg, opts...)
+		}
+
+		if videoOption.Subtitle != nil && len(videoOption.SubtitleMode) == 0 {
+			msg, opts := createSubtitleModesMessage(videoOption, h.vs.GetSubtitleModeOptions(videoOption))
+			return m.Send(msg, opts...)
+		}
+
+		if videoOption.Playlist != nil {
+			if err = h.ls.Check(m.Sender().ID, 1, 0); err != nil {
+				return err
+			}
+
+			// a playlist longer than the hourly quota is cut to the remaining downloads
+			remaining, err := h.ls.RemainingDownloads(m.Sender().ID)
+			if err != nil {
+				return err
+			}
+			if entries := videoOption.Playlist.Entries; remaining >= 0 && len(entries) > remaining {
+				// the cached option is not changed
+				opt, playlist := *videoOption, *videoOption.Playlist
+				playlist.Entries = entries[:remaining]
+				opt.Playlist = &playlist
+				videoOption = &opt
+
+				m.Send(fmt.Sprintf("only first %d of %d entries are queued because of the downloads per hour limit", remaining, len(entries)))
+			}
+
+			return h.enqueuePlaylist(m, videoOption)
 		}
 
-		path, err := h.vs.DownloadVideo(ctx, videoOption)
+		return h.startJob(m, userbotClient, videoOption)
+	}
+}
+
+// startJob sends the file uploaded earlier or queues the download of the option to the current chat,
+// opts are applied to the status message.
+func (h *TelegramMessageHandler) startJob(m telebot.Context, userbotClient *telegram.UserBotClient, videoOption *models.VideoOption, opts ...any) (err error) {
+	job := &models.Job{
+		ID:          uuid.New().String(),
+		UserID:      m.Sender().ID,
+		ChatID:      m.Chat().ID,
+		Username:    m.Sender().Username,
+		VideoOption: *applyUserSettings(h.ss.Get(m.Sender().ID), videoOption),
+		CreatedAt:   time.Now(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	if sent, err := h.sendCachedMedia(ctx, userbotClient, job); err != nil || sent {
+		return err
+	}
+
+	if err = h.ls.Check(job.UserID, 1, videoOption.Size); err != nil {
+		return err
+	}
+
+	statusMsg, err := m.Bot().Send(m.Chat(), "preparing download", append(opts, cancelJobMarkup(job.ID))...)
+	if err != nil {
+		return err
+	}
+	job.StatusMessageID = statusMsg.ID
+
+	position, err := h.qs.Enqueue(job)
+	if err != nil {
+		defer m.Bot().Delete(statusMsg)
+		return err
+	}
+
+	if err = h.ls.RecordDownloads(job.UserID, 1); err != nil {
+		log.Logger.Errorw("failed to record download", "user", job.UserID, "error", err)
+	}
+
+	if position > 0 {
+		_, err = m.Bot().Edit(statusMsg, fmt.Sprintf("download queued, you are #%d in queue", position), cancelJobMarkup(job.ID))
+		return err
+	}
+
+	return nil
+}
+
+// enqueuePlaylist queues a job per playlist entry, entries are downloaded one by one.
+func (h *TelegramMessageHandler) enqueuePlaylist(m telebot.Context, videoOption *models.VideoOption) error {
+	entryOptions := PlaylistEntryOptions(applyUserSettings(h.ss.Get(m.Sender().ID), videoOption))
+	batchID := uuid.New().String()
+
+	for i, entryOption := range entryOptions {
+		job := &models.Job{
+			ID:          uuid.New().String(),
+			UserID:      m.Sender().ID,
+			ChatID:      m.Chat().ID,
+			Username:    m.Sender().Username,
+			VideoOption: entryOption,
+			BatchID:     batchID,
+			BatchIndex:  i + 1,
+			BatchSize:   len(entryOptions),
+			CreatedAt:   time.Now(),
+		}
+
+		if _, err := h.qs.Enqueue(job); err != nil {
+			return err
+		}
+	}
+
+	if err := h.ls.RecordDownloads(m.Sender().ID, len(entryOptions)); err != nil {
+		log.Logger.Errorw("failed to record download", "user", m.Sender().ID, "error", err)
+	}
+
+	return m.Send(fmt.Sprintf("%d downloads of '%s' queued, use /queue to see progress and /cancel to stop", len(entryOptions), videoOption.Playlist.Title))
+}
+
+func (h *TelegramMessageHandler) OnQueue() telebot.HandlerFunc {
+	return func(m telebot.Context) (err error) {
+		jobs := h.qs.UserJobs(m.Sender().ID)
+		if len(jobs) == 0 {
+			return m.Send("you have no queued downloads")
+		}
+
+		lines := make([]string, 0, len(jobs))
+		for _, job := range jobs {
+			status := "downloading"
+			if position := h.qs.Position(job.ID); position > 0 {
+				status = fmt.Sprintf("#%d in queue", position)
+			}
+
+			lines = append(lines, fmt.Sprintf("%s %s - %s", job.VideoOption.Label, job.VideoOption.VideoInfo.Title, status))
+		}
+
+		return m.Send(strings.Join(lines, "\n"))
+	}
+}
+
+func (h *TelegramMessageHandler) OnCancel() telebot.HandlerFunc {
+	return func(m telebot.Context) (err error) {
+		var cancelled int
+		for _, job := range h.qs.UserJobs(m.Sender().ID) {
+			if h.qs.Cancel(job.ID) {
+				jobStatusMessage(m.Bot(), job, nil).Finish("download cancelled")
+				cancelled++
+			}
+		}
+
+		if cancelled == 0 {
+			return m.Send("you have no queued downloads")
+		}
+
+		return m.Send(fmt.Sprintf("%d download(s) cancelled", cancelled))
+	}
+}
+
+func (h *TelegramMessageHandler) OnCancelJob() telebot.HandlerFunc {
+	return func(m telebot.Context) (err error) {
+		jobID := m.Callback().Data
+
+		job, ok := h.qs.Job(jobID)
+		if !ok || job.UserID != m.Sender().ID {
+			return m.Respond(&telebot.CallbackResponse{Text: "download is already finished"})
+		}
+
+		h.qs.Cancel(jobID)
+
+		jobStatusMessage(m.Bot(), job, nil).Finish("download cancelled")
+
+		return m.Respond(&telebot.CallbackResponse{Text: "download cancelled"})
+	}
+}
+
+// ProcessJob returns a queue handler, which downloads the video and uploads it to the job's chat.
+func (h *TelegramMessageHandler) ProcessJob(bot *telebot.Bot, userbotClient *telegram.UserBotClient) JobHandler {
+	return func(ctx context.Context, job *models.Job) (err error) {
+		chat := telebot.ChatID(job.ChatID)
+
+		if job.StatusMessageID == 0 && len(job.InlineMessageID) == 0 {
+			statusMsg, err := bot.Send(chat, jobStatusPrefix(job)+"preparing download", cancelJobMarkup(job.ID))
+			if err != nil {
+				return err
+			}
+			job.StatusMessageID = statusMsg.ID
+		}
+		status := jobStatusMessage(bot, job, cancelJobMarkup(job.ID))
+		status.prefix = jobStatusPrefix(job)
+
+		defer func() {
+			if err == nil {
+				// the inline message is replaced with the file
+				if len(job.InlineMessageID) == 0 {
+					status.Delete()
+				}
+				return
+			}
+
+			if errors.Is(context.Cause(ctx), ErrJobCancelled) {
+				status.Finish("download cancelled")
+				return
+			}
+
+			if !errors.Is(err, context.Canceled) {
+				errMsg := "error on upload: '%s'"
+				if errors.Is(err, new(dlpError)) {
+					errMsg = "error on download: '%s'"
+				}
+				status.Finish(fmt.Sprintf(errMsg, err))
+			}
+		}()
+
+		jobCtx, cancel := context.WithTimeout(ctx, 1*time.Hour)
+		defer cancel()
+
+		if sent, err := h.sendCachedMedia(jobCtx, userbotClient, job); err != nil || sent {
+			return err
+		}
+
+		status.Set("downloading")
+
+		videoOption := &job.VideoOption
+
+		switch {
+		case len(job.InlineMessageID) > 0:
+		case videoOption.Audio || videoOption.SubtitleFile():
+			_ = bot.Notify(chat, telebot.UploadingDocument)
+		default:
+			_ = bot.Notify(chat, telebot.UploadingVideo)
+		}
+
+		path, err := h.downloadVideo(jobCtx, job, status.DownloadProgress)
 		if err != nil {
 			return err
 		}
 		defer os.Remove(path)
 
-		log.Logger.Infow("video downloaded", "path", path)
+		log.Logger.Infow("video downloaded", "path", path, "job", job.ID)
 
-		err = userbotClient.UploadFile(ctx, &tg.InputPeerUser{UserID: m.Sender().ID}, videoOption, path)
+		fileInfo, err := os.Stat(path)
 		if err != nil {
 			return err
 		}
 
+		if err = h.ls.RecordBytes(job.UserID, uint64(fileInfo.Size())); err != nil {
+			log.Logger.Errorw("failed to record downloaded bytes", "user", job.UserID, "error", err)
+		}
+
+		if len(job.InlineMessageID) > 0 {
+			return h.deliverInline(jobCtx, userbotClient, status, job, path, uint64(fileInfo.Size()))
+		}
+
+		if videoOption.SplitChapters {
+			return h.deliverChapters(jobCtx, userbotClient, status, job, path)
+		}
+
+		if size := uint64(fileInfo.Size()); h.vs.IsOversized(size) {
+			return h.deliverOversized(jobCtx, bot, userbotClient, status, job, path, size)
+		}
+
+		status.Set("uploading")
+
+		media, err := h.uploadFile(job, path, func() (*models.CachedMedia, error) {
+			return userbotClient.UploadFile(jobCtx, telegram.PeerFromChatID(job.ChatID), videoOption, path, status.UploadProgress)
+		})
+		if err != nil {
+			return err
+		}
+
+		h.vs.SaveCachedMedia(videoOption, media)
+
 		return nil
 	}
 }
 
-func (h *TelegramMessageHandler) OnNewMessage() telebot.HandlerFunc {
+// deliverOversized splits the file, which exceeds upload limit, into parts or sends a link to it.
+func (h *TelegramMessageHandler) deliverOversized(ctx context.Context, bot *telebot.Bot, userbotClient *telegram.UserBotClient, status *statusMessage, job *models.Job, path string, size uint64) error {
+	videoOption := &job.VideoOption
+
+	if h.conf.Upload.Oversize == OversizeSplit {
+		status.Set("file is too large, splitting into parts")
+
+		parts, err := h.vs.SplitFile(ctx, videoOption, path, size)
+		if err == nil {
+			defer removeFiles(parts)
+
+			for i, part := range parts {
+				partOption := *videoOption
+				partOption.Label += fmt.Sprintf(" part %d of %d", i+1, len(parts))
+				partOption.VideoInfo.Title += fmt.Sprintf(" (%d/%d)", i+1, len(parts))
+
+				status.Set(fmt.Sprintf("uploading part %d of %d", i+1, len(parts)))
+
+				_, err = h.uploadFile(job, part, func() (*models.CachedMedia, error) {
+					return userbotClient.UploadFile(ctx, telegram.PeerFromChatID(job.ChatID), &partOption, part, status.UploadProgress)
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+
+		if len(h.conf.Upload.LinkURL) == 0 {
+			return err
+		}
+		log.Logger.Warnw("failed to split file, falling back to link", "job", job.ID, "error", err)
+	}
+
+	link, err := h.vs.PublishFile(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = bot.Send(telebot.ChatID(job.ChatID), fmt.Sprintf("%s - %s is too large for Telegram (%s), download it here: %s", videoOption.Label, videoOption.VideoInfo.Title, humanize.Bytes(size), link))
+
+	return err
+}
+
+// sendCachedMedia resends the file uploaded earlier for the same video and format, if any. False without
+// an error means the file must be downloaded.
+func (h *TelegramMessageHandler) sendCachedMedia(ctx context.Context, userbotClient *telegram.UserBotClient, job *models.Job) (bool, error) {
+	media, ok := h.vs.GetCachedMedia(&job.VideoOption)
+	if !ok {
+		return false, nil
+	}
+
+	var err error
+	if len(job.InlineMessageID) > 0 {
+		err = userbotClient.EditInlineMessage(ctx, job.InlineMessageID, &job.VideoOption, media)
+	} else {
+		err = userbotClient.SendCachedFile(ctx, telegram.PeerFromChatID(job.ChatID), &job.VideoOption, media)
+	}
+	if telegram.IsFileReferenceError(err) {
+		// the file is uploaded again
+		log.Logger.Warnw("cached media is expired", "job", job.ID, "error", err)
+		h.vs.DeleteCachedMedia(&job.VideoOption)
+
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (h *TelegramMessageHandler) OnNewMessage(userbotClient *telegram.UserBotClient) telebot.HandlerFunc {
 	return func(m telebot.Context) (err error) {
+		if m.Chat().Type != telebot.ChatPrivate {
+			return h.onGroupMessage(m, userbotClient)
+		}
+
 		defer func() {
 			if err != nil {
-				defer m.Bot().Send(m.Sender(), fmt.Sprintf("error: '%s'", err))
+				defer m.Bot().Send(m.Sender(), errorText(err))
 			}
 		}()
 
-		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-		defer cancel()
+		if err = h.ls.Check(m.Sender().ID, 0, 0); err != nil {
+			return err
+		}
+
+		requests := h.parseVideoRequests(m.Sender().ID, m.Message())
+		if len(requests) == 0 {
+			// captions of media without links are not search queries
+			if m.Message().Media() != nil {
+				return ErrInvalidURL
+			}
+
+			query := strings.TrimSpace(m.Text())
+			if len(query) == 0 || strings.HasPrefix(query, "/") {
+				return ErrInvalidURL
+			}
+
+			return h.sendSearchResults(m, query)
+		}
+
+		if maxLinks := h.conf.Limits.LinksPerMessage; len(requests) > maxLinks {
+			requests = requests[:maxLinks]
+			m.Send(fmt.Sprintf("only first %d links are processed", maxLinks))
+		}
 
 		tmpMsg, err := m.Bot().Send(m.Sender(), "gathering info")
 		if err != nil {
@@ -109,36 +480,218 @@ func (h *TelegramMessageHandler) OnNewMessage() telebot.HandlerFunc {
 
 		m.Notify(telebot.Typing)
 
-		videoURL, err := fetchFirstURL(m.Text())
-		if err != nil {
-			videoURL = m.Text()
+		settings := h.ss.Get(m.Sender().ID)
+		process := func(ctx context.Context, req videoRequest) error {
+			if settings.AutoDownload {
+				return h.autoDownload(ctx, m, userbotClient, req.url, req.clip, DefaultQuality(settings), settings.SubtitleLang)
+			}
+
+			return h.sendVideoInfo(ctx, m, req.url, req.clip)
 		}
 
-		videoInfo, json, err := h.vs.GetVideoInfo(ctx, videoURL)
-		if err != nil {
-			return err
+		if len(requests) == 1 {
+			ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+			defer cancel()
+
+			return process(ctx, requests[0])
 		}
 
-		videoOpts, err := h.vs.GetVideoOptions(videoInfo, json)
+		// a card per link, failed links do not stop the rest
+		for _, req := range requests {
+			ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+			if errS := process(ctx, req); errS != nil {
+				m.Send(req.url+"\n"+errorText(errS), telebot.NoPreview)
+			}
+			cancel()
+		}
+
+		return nil
+	}
+}
+
+// sendVideoInfo sends download options of the video or the playlist, options download only the clip if it is set.
+// sendOpts are applied to the sent message.
+func (h *TelegramMessageHandler) sendVideoInfo(ctx context.Context, m telebot.Context, videoURL string, clip *models.Clip, sendOpts ...any) error {
+	videoInfo, playlist, err := h.vs.GetVideoInfo(ctx, videoURL)
+	if err != nil {
+		return err
+	}
+
+	if playlist != nil {
+		msg, opts := createPlaylistInfoMessage(playlist, h.vs.GetPlaylistOptions(playlist))
+		return m.Send(msg, append(opts, sendOpts...)...)
+	}
+
+	videoOpts, err := h.vs.GetVideoOptions(videoInfo)
+	if err != nil {
+		return err
+	}
+
+	if clip != nil {
+		videoOpts, err = h.vs.GetClipOptions(videoInfo, videoOpts, clip)
 		if err != nil {
 			return err
 		}
+	} else {
+		// subtitles are not cut, so they are offered for the whole video only.
+		// Menus are based on the plain options, so they do not inherit the preferred subtitles.
+		var extraOpts []*models.VideoOption
+
+		// the preferred quality with subtitles of the preferred language
+		if settings := h.ss.Get(m.Sender().ID); len(settings.SubtitleLang) > 0 {
+			if opt, ok := DefaultOption(videoOpts, settings.Quality); ok && !opt.Audio {
+				if subOpt, found := h.vs.GetPreferredSubtitleOption(videoInfo, opt, settings.SubtitleLang); found {
+					extraOpts = append(extraOpts, subOpt)
+				}
+			}
+		}
 
-		msg, opts := createVideoInfoMessage(videoInfo, videoOpts)
-		return m.Send(msg, opts...)
+		if subtitlesOpt, ok := h.vs.GetSubtitlesOption(videoInfo, videoOpts); ok {
+			extraOpts = append(extraOpts, subtitlesOpt)
+		}
+
+		if chaptersOpt, ok := h.vs.GetChaptersOption(videoInfo, videoOpts); ok {
+			extraOpts = append(extraOpts, chaptersOpt)
+		}
+
+		videoOpts = append(videoOpts, extraOpts...)
 	}
+
+	msg, opts := createVideoInfoMessage(videoInfo, videoOpts)
+	return m.Send(msg, append(opts, sendOpts...)...)
+}
+
+// downloadVideo downloads the option of the job, the download is counted in stats.
+func (h *TelegramMessageHandler) downloadVideo(ctx context.Context, job *models.Job, onProgress ProgressFunc) (string, error) {
+	done := h.st.Begin(job, ActivityDownload)
+
+	path, err := h.vs.DownloadVideo(ctx, &job.VideoOption, onProgress)
+	done(fileSize(path), err)
+
+	return path, err
+}
+
+// uploadFile calls upload of the file, the upload is counted in stats.
+func (h *TelegramMessageHandler) uploadFile(job *models.Job, path string, upload func() (*models.CachedMedia, error)) (*models.CachedMedia, error) {
+	done := h.st.Begin(job, ActivityUpload)
+
+	media, err := upload()
+	done(fileSize(path), err)
+
+	return media, err
+}
+
+func fileSize(path string) uint64 {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+
+	return uint64(fileInfo.Size())
+}
+
+func jobStatusPrefix(job *models.Job) string {
+	if job.BatchSize == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("[%d/%d] %s\n", job.BatchIndex, job.BatchSize, job.VideoOption.VideoInfo.Title)
+}
+
+func createPlaylistInfoMessage(playlist *models.PlaylistInfo, opts []*models.VideoOption) (msg any, options []any) {
+	text := fmt.Sprintf("%s\n\n%d videos, %s total", playlist.Title, len(playlist.Entries), time.Duration(playlist.Duration())*time.Second)
+	if playlist.Total > len(playlist.Entries) {
+		text += fmt.Sprintf("\nonly first %d of %d videos will be downloaded", len(playlist.Entries), playlist.Total)
+	}
+	text += "\n\ndownload all:"
+
+	inlineMenu := &telebot.ReplyMarkup{}
+
+	rows := make([]telebot.Row, 0, len(opts))
+	for _, opt := range opts {
+		emoji := videoEmoji
+		if opt.Audio {
+			emoji = audioEmoji
+		}
+
+		rows = append(rows, inlineMenu.Row(inlineMenu.Data(emoji+" "+opt.Label, opt.ID)))
+	}
+
+	inlineMenu.Inline(rows...)
+
+	return text, []any{inlineMenu}
+}
+
+func createSubtitleTracksMessage(subtitlesOpt *models.VideoOption, opts []*models.VideoOption) (msg any, options []any) {
+	inlineMenu := &telebot.ReplyMarkup{}
+
+	rows := make([]telebot.Row, 0, len(opts))
+	for _, opt := range opts {
+		rows = append(rows, inlineMenu.Row(inlineMenu.Data(subtitlesEmoji+" "+opt.Subtitle.Label(), opt.ID)))
+	}
+
+	inlineMenu.Inline(rows...)
+
+	return subtitlesOpt.VideoInfo.Title + "\n\nchoose subtitles language:", []any{inlineMenu}
+}
+
+func createSubtitleModesMessage(trackOpt *models.VideoOption, opts []*models.VideoOption) (msg any, options []any) {
+	inlineMenu := &telebot.ReplyMarkup{}
+
+	rows := make([]telebot.Row, 0, len(opts))
+	for _, opt := range opts {
+		mode := models.SubtitleModeByName(opt.SubtitleMode)
+
+		title := mode.Label
+		if !mode.File() {
+			title += " (" + opt.Label + ", " + humanize.Bytes(opt.Size) + ")"
+		}
+
+		rows = append(rows, inlineMenu.Row(inlineMenu.Data(title, opt.ID)))
+	}
+
+	inlineMenu.Inline(rows...)
+
+	return fmt.Sprintf("%s\n\nhow to send %s subtitles?", trackOpt.VideoInfo.Title, trackOpt.Subtitle.Label()), []any{inlineMenu}
+}
+
+func errorText(err error) string {
+	var quotaErr *QuotaError
+	if errors.As(err, &quotaErr) {
+		return quotaErr.Error()
+	}
+
+	return fmt.Sprintf("error: '%s'", err)
+}
+
+func cancelJobMarkup(jobID string) *telebot.ReplyMarkup {
+	menu := &telebot.ReplyMarkup{}
+	menu.Inline(menu.Row(menu.Data("Cancel", CancelJobButton.Unique, jobID)))
+
+	return menu
 }
 
 func createVideoInfoMessage(info *models.VideoInfo, opts []*models.VideoOption) (msg any, options []any) {
+	caption := info.Title
+	if len(opts) > 0 && opts[0].Clip != nil {
+		caption += "\n" + clipEmoji + " " + opts[0].Clip.String()
+	}
+	for _, opt := range opts {
+		if opt.Oversized {
+			caption += "\n\n" + oversizedEmoji + " the file exceeds Telegram limit and will be sent in parts or as a link"
+			break
+		}
+	}
+
 	if len(info.ThumbURL) > 0 {
 		msg = &telebot.Photo{
 			File: telebot.File{
 				FileURL: info.ThumbURL,
 			},
-			Caption: info.Title,
+			Caption: caption,
 		}
 	} else {
-		msg = info.Title
+		msg = caption
 	}
 
 	if len(opts) > 0 {
@@ -152,10 +705,25 @@ func createVideoInfoMessage(info *models.VideoInfo, opts []*models.VideoOption)
 			}
 
 			title := emoji + " " + opt.Label + " (" + humanize.Bytes(opt.Size) + ")"
+			if opt.Oversized {
+				title += " " + oversizedEmoji
+			}
+			if len(opt.SubtitleTracks) > 0 {
+				title = subtitlesEmoji + " Subtitles"
+			}
+
+			if opt.SplitChapters {
+				rows = append(rows, inlineMenu.Row(inlineMenu.Data(chaptersEmoji+" Chapters", ChaptersButton.Unique, opt.ID)))
+				continue
+			}
 
 			rows = append(rows, inlineMenu.Row(inlineMenu.Data(title, opt.ID)))
 		}
 
+		if info.Duration > 0 && opts[0].Clip == nil {
+			rows = append(rows, inlineMenu.Row(inlineMenu.Data(clipEmoji+" Trim", TrimButton.Unique, opts[0].ID)))
+		}
+
 		inlineMenu.Inline(rows...)
 
 		options = append(options, inlineMenu)
@@ -164,6 +732,56 @@ func createVideoInfoMessage(info *models.VideoInfo, opts []*models.VideoOption)
 	return
 }
 
+// extractURLs returns unique urls of the message text and its url and text_link entities in order of appearance.
+// extractURLs returns links of the message text or of the media caption.
+func extractURLs(msg *telebot.Message) []string {
+	var urls []string
+	for _, entity := range append(msg.Entities, msg.CaptionEntities...) {
+		switch entity.Type {
+		case telebot.EntityURL:
+			urls = append(urls, msg.EntityText(entity))
+		case telebot.EntityTextLink:
+			urls = append(urls, entity.URL)
+		}
+	}
+
+	// link entities are missing in messages sent by some clients
+	if len(urls) == 0 {
+		urls = urlRegex.FindAllString(messageText(msg), -1)
+	}
+
+	seen := make(map[string]bool, len(urls))
+	result := make([]string, 0, len(urls))
+	for _, u := range urls {
+		u = normalizeURL(u)
+
+		if !seen[u] {
+			seen[u] = true
+			result = append(result, u)
+		}
+	}
+
+	return result
+}
+
+// messageText returns the text of the message or the caption of the media.
+func messageText(msg *telebot.Message) string {
+	if len(msg.Text) > 0 {
+		return msg.Text
+	}
+
+	return msg.Caption
+}
+
+// normalizeURL adds the scheme to links like "youtu.be/x", which Telegram detects without it.
+func normalizeURL(u string) string {
+	if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+		return "https://" + u
+	}
+
+	return u
+}
+
 func fetchFirstURL(input string) (string, error) {
 	regex := regexp.MustCompile(`^https?://[^\s"]+$`)
 
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
+	"gopkg.in/telebot.v3"
+)
+
+// AccessMiddleware drops updates from users and chats, which are not allowed to use the bot.
+func (h *TelegramMessageHandler) AccessMiddleware() telebot.MiddlewareFunc {
+	return func(next telebot.HandlerFunc) telebot.HandlerFunc {
+		return func(m telebot.Context) error {
+			sender, chat := m.Sender(), m.Chat()
+			if sender == nil {
+				return nil
+			}
+
+			chatID := sender.ID
+			if chat != nil {
+				chatID = chat.ID
+			}
+
+			if h.as.IsAllowed(sender.ID, sender.Username, chatID) {
+				if chatID == sender.ID {
+					h.st.RecordUser(sender.ID, sender.Username)
+				}
+				return next(m)
+			}
+
+			log.Logger.Infow("access denied", "user", sender.ID, "username", sender.Username, "chat", chatID)
+
+			switch {
+			case m.Callback() != nil:
+				return m.Respond(&telebot.CallbackResponse{Text: "access denied", ShowAlert: true})
+			case m.Query() != nil:
+				return m.Answer(&telebot.QueryResponse{
+					SwitchPMText:      "access denied",
+					SwitchPMParameter: "access",
+					IsPersonal:        true,
+				})
+			case m.InlineResult() != nil:
+				return nil
+			}
+
+			// keep silence in groups, so the bot does not spam there
+			if chat == nil || chat.Type == telebot.ChatPrivate {
+				return m.Send(fmt.Sprintf("access denied, ask the bot admin to allow your user ID %d", sender.ID))
+			}
+
+			return nil
+		}
+	}
+}
+
+// AdminMiddleware passes only updates from admins.
+func (h *TelegramMessageHandler) AdminMiddleware() telebot.MiddlewareFunc {
+	return func(next telebot.HandlerFunc) telebot.HandlerFunc {
+		return func(m telebot.Context) error {
+			if m.Sender() == nil || !h.as.IsAdmin(m.Sender().ID) {
+				return nil
+			}
+
+			return next(m)
+		}
+	}
+}
+
+func (h *TelegramMessageHandler) OnAllow() telebot.HandlerFunc {
+	return func(m telebot.Context) error {
+		if len(m.Args()) == 0 {
+			return m.Send("usage: /allow <user ID | @username | group chat ID> ...")
+		}
+
+		for _, subject := range m.Args() {
+			if err := h.as.Allow(subject); err != nil {
+				return m.Send(fmt.Sprintf("failed to allow '%s': %s", subject, err))
+			}
+		}
+
+		return m.Send("allowed")
+	}
+}
+
+func (h *TelegramMessageHandler) OnDeny() telebot.HandlerFunc {
+	return func(m telebot.Context) error {
+		if len(m.Args()) == 0 {
+			return m.Send("usage: /deny <user ID | @username | group chat ID> ...")
+		}
+
+		for _, subject := range m.Args() {
+			if err := h.as.Deny(subject); err != nil {
+				return m.Send(fmt.Sprintf("failed to deny '%s': %s", subject, err))
+			}
+		}
+
+		return m.Send("denied")
+	}
+}
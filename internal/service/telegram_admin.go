@@ -0,0 +1,232 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
+	"gopkg.in/telebot.v3"
+)
+
+const (
+	defaultStatsDays = 7
+	maxStatsDays     = 30
+
+	// shortJobIDLen is enough to tell jobs apart in /jobs and /kill
+	shortJobIDLen = 8
+
+	// broadcastInterval keeps broadcasts under Telegram limit of 30 messages per second
+	broadcastInterval = 50 * time.Millisecond
+)
+
+// OnStats shows downloads, bytes and errors per day and extractor, "/stats <days>" changes the period.
+func (h *TelegramMessageHandler) OnStats() telebot.HandlerFunc {
+	return func(m telebot.Context) error {
+		days := defaultStatsDays
+		if args := m.Args(); len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 || n > maxStatsDays {
+				return m.Send(fmt.Sprintf("usage: /stats [days, up to %d]", maxStatsDays))
+			}
+			days = n
+		}
+
+		stats, err := h.st.Days(days)
+		if err != nil {
+			return m.Send(errorText(err))
+		}
+
+		return m.Send(createStatsMessage(stats))
+	}
+}
+
+// OnJobs lists downloads and uploads in progress.
+func (h *TelegramMessageHandler) OnJobs() telebot.HandlerFunc {
+	return func(m telebot.Context) error {
+		_, pending := h.qs.Jobs()
+
+		var sb strings.Builder
+		for _, activity := range h.st.Active() {
+			job := activity.Job
+			sb.WriteString(fmt.Sprintf("%s %s %s, user %d: %s (%s)\n",
+				shortJobID(job.ID), activity.Stage, time.Since(activity.StartedAt).Round(time.Second),
+				job.UserID, job.VideoOption.VideoInfo.Title, job.VideoOption.Label))
+		}
+
+		if sb.Len() == 0 {
+			sb.WriteString("no active downloads or uploads\n")
+		}
+		sb.WriteString(fmt.Sprintf("\n%d jobs pending, use /kill <job> to stop a job", len(pending)))
+
+		return m.Send(sb.String(), telebot.NoPreview)
+	}
+}
+
+// OnKill cancels the job of any user by its ID or a prefix of it.
+func (h *TelegramMessageHandler) OnKill() telebot.HandlerFunc {
+	return func(m telebot.Context) error {
+		if len(m.Args()) != 1 {
+			return m.Send("usage: /kill <job>")
+		}
+
+		running, pending := h.qs.Jobs()
+
+		var found []*models.Job
+		for _, job := range append(running, pending...) {
+			if strings.HasPrefix(job.ID, m.Args()[0]) {
+				found = append(found, job)
+			}
+		}
+
+		switch {
+		case len(found) == 0:
+			return m.Send("job not found")
+		case len(found) > 1:
+			return m.Send("several jobs match, use a longer job ID")
+		case !h.qs.Cancel(found[0].ID):
+			return m.Send("job has already finished")
+		}
+
+		jobStatusMessage(m.Bot(), found[0], nil).Finish("download cancelled by admin")
+
+		return m.Send(fmt.Sprintf("job %s cancelled", shortJobID(found[0].ID)))
+	}
+}
+
+// OnBroadcast sends the message to every user, who has used the bot and is not denied.
+func (h *TelegramMessageHandler) OnBroadcast() telebot.HandlerFunc {
+	return func(m telebot.Context) error {
+		text := strings.TrimSpace(m.Message().Payload)
+		if len(text) == 0 {
+			return m.Send("usage: /broadcast <message>")
+		}
+
+		users, err := h.st.Users()
+		if err != nil {
+			return m.Send(errorText(err))
+		}
+
+		go func() {
+			var sent int
+			for _, user := range users {
+				if !h.as.IsAllowed(user.ID, user.Username, user.ID) {
+					continue
+				}
+
+				if _, errS := m.Bot().Send(&telebot.User{ID: user.ID}, text); errS != nil {
+					log.Logger.Debugw("failed to send broadcast", "user", user.ID, "error", errS)
+				} else {
+					sent++
+				}
+
+				time.Sleep(broadcastInterval)
+			}
+
+			m.Send(fmt.Sprintf("broadcast sent to %d of %d users", sent, len(users)))
+		}()
+
+		return m.Send(fmt.Sprintf("sending broadcast to %d users", len(users)))
+	}
+}
+
+// OnBan denies access to the user and cancels the user's jobs.
+func (h *TelegramMessageHandler) OnBan() telebot.HandlerFunc {
+	return func(m telebot.Context) error {
+		if len(m.Args()) != 1 {
+			return m.Send("usage: /ban <user ID | @username>")
+		}
+
+		subject := m.Args()[0]
+		if err := h.as.Deny(subject); err != nil {
+			return m.Send(fmt.Sprintf("failed to ban '%s': %s", subject, err))
+		}
+
+		var cancelled int
+		for _, job := range h.subjectJobs(subject) {
+			if h.qs.Cancel(job.ID) {
+				jobStatusMessage(m.Bot(), job, nil).Finish("download cancelled by admin")
+				cancelled++
+			}
+		}
+
+		return m.Send(fmt.Sprintf("banned, %d jobs cancelled", cancelled))
+	}
+}
+
+// subjectJobs returns running and pending jobs of the user given by ID or @username.
+func (h *TelegramMessageHandler) subjectJobs(subject string) []*models.Job {
+	if userID, err := strconv.ParseInt(subject, 10, 64); err == nil {
+		return h.qs.UserJobs(userID)
+	}
+
+	username := strings.TrimPrefix(subject, "@")
+
+	// jobs resumed from earlier versions have no username, they are found by known users
+	userIDs := make(map[int64]bool)
+	users, err := h.st.Users()
+	if err != nil {
+		log.Logger.Errorw("failed to get users", "error", err)
+	}
+	for _, user := range users {
+		if strings.EqualFold(user.Username, username) {
+			userIDs[user.ID] = true
+		}
+	}
+
+	running, pending := h.qs.Jobs()
+
+	var jobs []*models.Job
+	for _, job := range append(running, pending...) {
+		if strings.EqualFold(job.Username, username) || userIDs[job.UserID] {
+			jobs = append(jobs, job)
+		}
+	}
+
+	return jobs
+}
+
+func createStatsMessage(stats []*models.DailyStats) string {
+	var sb strings.Builder
+	for _, day := range stats {
+		var total models.ExtractorStats
+
+		extractors := make([]string, 0, len(day.Extractors))
+		for name, es := range day.Extractors {
+			extractors = append(extractors, name)
+
+			total.Downloads += es.Downloads
+			total.DownloadBytes += es.DownloadBytes
+			total.DownloadErrors += es.DownloadErrors
+			total.Uploads += es.Uploads
+			total.UploadBytes += es.UploadBytes
+			total.UploadErrors += es.UploadErrors
+		}
+		sort.Strings(extractors)
+
+		sb.WriteString(fmt.Sprintf("%s: %s\n", day.Date, statsLine(&total)))
+		for _, name := range extractors {
+			sb.WriteString(fmt.Sprintf("  %s: %s\n", name, statsLine(day.Extractors[name])))
+		}
+	}
+
+	return sb.String()
+}
+
+func statsLine(es *models.ExtractorStats) string {
+	return fmt.Sprintf("%d downloads, %s, %d errors / %d uploads, %s, %d errors",
+		es.Downloads, humanize.Bytes(es.DownloadBytes), es.DownloadErrors,
+		es.Uploads, humanize.Bytes(es.UploadBytes), es.UploadErrors)
+}
+
+func shortJobID(jobID string) string {
+	if len(jobID) > shortJobIDLen {
+		return jobID[:shortJobIDLen]
+	}
+
+	return jobID
+}
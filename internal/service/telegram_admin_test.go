@@ -0,0 +1,56 @@
+package service
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/far4599/telegram-bot-youtube-download/internal/repository"
+)
+
+func TestSubjectJobs(t *testing.T) {
+	q, _, db := openTestQueue(t, filepath.Join(t.TempDir(), "bot.db"), 1)
+	defer db.Close()
+
+	st := NewStatsService(repository.NewStatsRepository(db))
+	// the job of user 3 is resumed from a version without usernames in jobs
+	st.RecordUser(3, "Carol")
+
+	for _, job := range []*models.Job{
+		{ID: "a", UserID: 1, Username: "Alice"},
+		{ID: "b", UserID: 2},
+		{ID: "c", UserID: 1, Username: "Alice"},
+		{ID: "d", UserID: 3},
+	} {
+		if _, err := q.Enqueue(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := &TelegramMessageHandler{qs: q, st: st}
+
+	tests := []struct {
+		subject string
+		want    []string
+	}{
+		{subject: "1", want: []string{"a", "c"}},
+		{subject: "@alice", want: []string{"a", "c"}},
+		{subject: "carol", want: []string{"d"}},
+		{subject: "@nobody"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.subject, func(t *testing.T) {
+			var ids []string
+			for _, job := range h.subjectJobs(tt.subject) {
+				ids = append(ids, job.ID)
+			}
+			sort.Strings(ids)
+
+			if !equalIDs(ids, tt.want) {
+				t.Errorf("got jobs %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/telegram"
+	"gopkg.in/telebot.v3"
+)
+
+const chaptersEmoji = "📖"
+
+var (
+	ChaptersButton = telebot.Btn{Unique: "chapters"}
+	ChapterButton  = telebot.Btn{Unique: "chapter"}
+)
+
+// OnChapters sends the chapters menu of the video, callback data is an ID of the split chapters option.
+func (h *TelegramMessageHandler) OnChapters() telebot.HandlerFunc {
+	return func(m telebot.Context) (err error) {
+		defer m.Respond()
+
+		videoOption, ok := h.vs.getFromCache(m.Callback().Data)
+		if !ok {
+			return m.Send(errorText(ErrNotFound))
+		}
+
+		msg, opts := createChaptersMessage(videoOption)
+		return m.Send(msg, opts...)
+	}
+}
+
+// OnChapter sends download options of a single chapter, callback data is "<option ID>|<chapter index>".
+func (h *TelegramMessageHandler) OnChapter() telebot.HandlerFunc {
+	return func(m telebot.Context) (err error) {
+		defer func() {
+			if err != nil {
+				defer m.Bot().Send(m.Sender(), errorText(err))
+			}
+		}()
+
+		defer m.Respond()
+
+		optionID, index, ok := strings.Cut(m.Callback().Data, "|")
+		if !ok {
+			return ErrNotFound
+		}
+
+		videoOption, ok := h.vs.getFromCache(optionID)
+		if !ok {
+			return ErrNotFound
+		}
+
+		i, err := strconv.Atoi(index)
+		if err != nil || i < 0 || i >= len(videoOption.VideoInfo.Chapters) {
+			return ErrNotFound
+		}
+
+		if err = h.ls.Check(m.Sender().ID, 0, 0); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+
+		tmpMsg, err := m.Bot().Send(m.Sender(), "gathering info")
+		if err != nil {
+			return err
+		}
+		defer m.Bot().Delete(tmpMsg)
+
+		info := &videoOption.VideoInfo
+
+		return h.sendVideoInfo(ctx, m, info.URL, ChapterClip(info, info.Chapters[i]))
+	}
+}
+
+// deliverChapters splits the downloaded audio by chapters and uploads each chapter as a separate track.
+func (h *TelegramMessageHandler) deliverChapters(ctx context.Context, userbotClient *telegram.UserBotClient, status *statusMessage, job *models.Job, path string) error {
+	status.Set("splitting into chapters")
+
+	trackOptions, paths, err := h.vs.SplitChapters(ctx, &job.VideoOption, path)
+	if err != nil {
+		return err
+	}
+	defer removeFiles(paths)
+
+	for i := range trackOptions {
+		status.Set(fmt.Sprintf("uploading chapter %d of %d", i+1, len(trackOptions)))
+
+		_, err = h.uploadFile(job, paths[i], func() (*models.CachedMedia, error) {
+			return userbotClient.UploadFile(ctx, telegram.PeerFromChatID(job.ChatID), &trackOptions[i], paths[i], status.UploadProgress)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func createChaptersMessage(chaptersOpt *models.VideoOption) (msg any, options []any) {
+	info := chaptersOpt.VideoInfo
+
+	text := fmt.Sprintf("%s\n\n%d chapters", info.Title, len(info.Chapters))
+	if len(info.Chapters) > maxChapters {
+		text += fmt.Sprintf(", only first %d are listed", maxChapters)
+	}
+
+	inlineMenu := &telebot.ReplyMarkup{}
+
+	rows := []telebot.Row{
+		inlineMenu.Row(inlineMenu.Data(audioEmoji+" all chapters as "+models.AudioCodecMP3.Name+" tracks", chaptersOpt.ID)),
+	}
+	for i, chapter := range info.Chapters {
+		if i >= maxChapters {
+			break
+		}
+
+		title := models.FormatTimestamp(int(chapter.StartTime)) + " " + chapter.Title
+		rows = append(rows, inlineMenu.Row(inlineMenu.Data(title, ChapterButton.Unique, chaptersOpt.ID+"|"+strconv.Itoa(i))))
+	}
+
+	inlineMenu.Inline(rows...)
+
+	return text, []any{inlineMenu}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"gopkg.in/telebot.v3"
+)
+
+// trimTimeout is how long the bot waits for a time range after Trim is pressed.
+const trimTimeout = 10 * time.Minute
+
+var TrimButton = telebot.Btn{Unique: "trim"}
+
+type pendingTrim struct {
+	url       string
+	expiresAt time.Time
+}
+
+// OnTrim asks the user for a time range, the next message with a range trims the video of the pressed option.
+func (h *TelegramMessageHandler) OnTrim() telebot.HandlerFunc {
+	return func(m telebot.Context) (err error) {
+		defer m.Respond()
+
+		videoOption, ok := h.vs.getFromCache(m.Callback().Data)
+		if !ok {
+			return m.Send(errorText(ErrNotFound))
+		}
+
+		h.trimMu.Lock()
+		h.trims[m.Sender().ID] = pendingTrim{
+			url:       videoOption.VideoInfo.URL,
+			expiresAt: time.Now().Add(trimTimeout),
+		}
+		h.trimMu.Unlock()
+
+		text := fmt.Sprintf("send me a time range to download, e.g. 1:23-2:45\nthe video is %s long", models.FormatTimestamp(videoOption.VideoInfo.Duration))
+		if clip, ok := clipFromURL(videoOption.VideoInfo.URL); ok {
+			text += fmt.Sprintf(", the link starts at %s", models.FormatTimestamp(clip.Start))
+		}
+
+		return m.Send(text)
+	}
+}
+
+type videoRequest struct {
+	url  string
+	clip *models.Clip
+}
+
+// parseVideoRequests extracts video urls of the message, each with an optional clip range typed right after
+// the url ("URL 1:23-2:45"). A bare range sent after Trim is pressed applies to the pending video.
+func (h *TelegramMessageHandler) parseVideoRequests(userID int64, msg *telebot.Message) []videoRequest {
+	fields := strings.Fields(messageText(msg))
+
+	if len(fields) == 1 {
+		if clip, ok := parseClipRange(fields[0]); ok {
+			if videoURL, ok := h.takePendingTrim(userID); ok {
+				return []videoRequest{{url: videoURL, clip: clip}}
+			}
+		}
+	}
+
+	// keyed the same way as extractURLs results
+	ranges := make(map[string]*models.Clip)
+	for i := 0; i+1 < len(fields); i++ {
+		if clip, ok := parseClipRange(fields[i+1]); ok {
+			ranges[normalizeURL(fields[i])] = clip
+		}
+	}
+
+	var requests []videoRequest
+	for _, videoURL := range extractURLs(msg) {
+		requests = append(requests, videoRequest{url: videoURL, clip: ranges[videoURL]})
+	}
+
+	return requests
+}
+
+func (h *TelegramMessageHandler) takePendingTrim(userID int64) (string, bool) {
+	h.trimMu.Lock()
+	defer h.trimMu.Unlock()
+
+	trim, ok := h.trims[userID]
+	delete(h.trims, userID)
+
+	if !ok || time.Now().After(trim.expiresAt) {
+		return "", false
+	}
+
+	return trim.url, true
+}
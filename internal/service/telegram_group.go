@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/telegram"
+	"gopkg.in/telebot.v3"
+)
+
+// onGroupMessage quietly looks for links in the group message and replies to it with the download menu,
+// or downloads the links right away in auto mode. Errors are logged only, so the group is not spammed.
+func (h *TelegramMessageHandler) onGroupMessage(m telebot.Context, userbotClient *telegram.UserBotClient) error {
+	settings := h.gs.Settings(m.Chat().ID)
+	if settings.Mode == GroupModeOff {
+		return nil
+	}
+
+	urls := extractURLs(m.Message())
+	if len(urls) == 0 {
+		return nil
+	}
+	if maxLinks := h.conf.Limits.LinksPerMessage; len(urls) > maxLinks {
+		urls = urls[:maxLinks]
+	}
+
+	// links of users over quota are not probed
+	if err := h.ls.Check(m.Sender().ID, 0, 0); err != nil {
+		log.Logger.Debugw("group link skipped", "chat", m.Chat().ID, "user", m.Sender().ID, "error", err)
+		return nil
+	}
+
+	reply := &telebot.SendOptions{ReplyTo: m.Message()}
+
+	for _, videoURL := range urls {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+
+		var err error
+		if settings.Mode == GroupModeAuto {
+			err = h.autoDownload(ctx, m, userbotClient, videoURL, nil, settings.Quality, "", reply)
+		} else {
+			err = h.sendVideoInfo(ctx, m, videoURL, nil, reply)
+		}
+		cancel()
+
+		if err != nil {
+			log.Logger.Debugw("failed to process group link", "chat", m.Chat().ID, "url", videoURL, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// autoDownload queues the video of the given quality, playlists and videos without such quality get the menu.
+// Subtitles of subtitleLang are embedded into the whole video if the video has them.
+func (h *TelegramMessageHandler) autoDownload(ctx context.Context, m telebot.Context, userbotClient *telegram.UserBotClient, videoURL string, clip *models.Clip, quality, subtitleLang string, opts ...any) error {
+	videoInfo, playlist, err := h.vs.GetVideoInfo(ctx, videoURL)
+	if err != nil {
+		return err
+	}
+
+	if playlist != nil {
+		msg, menu := createPlaylistInfoMessage(playlist, h.vs.GetPlaylistOptions(playlist))
+		return m.Send(msg, append(menu, opts...)...)
+	}
+
+	videoOpts, err := h.vs.GetVideoOptions(videoInfo)
+	if err != nil {
+		return err
+	}
+
+	if clip != nil {
+		videoOpts, err = h.vs.GetClipOptions(videoInfo, videoOpts, clip)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opt, ok := DefaultOption(videoOpts, quality); ok {
+		if clip == nil && !opt.Audio && len(subtitleLang) > 0 {
+			if subOpt, found := h.vs.GetPreferredSubtitleOption(videoInfo, opt, subtitleLang); found {
+				opt = subOpt
+			}
+		}
+
+		return h.startJob(m, userbotClient, opt, opts...)
+	}
+
+	msg, menu := createVideoInfoMessage(videoInfo, videoOpts)
+	return m.Send(msg, append(menu, opts...)...)
+}
+
+// OnGroupSettings shows settings of the group, group admins may change them with "/group <mode|quality> <value>".
+func (h *TelegramMessageHandler) OnGroupSettings() telebot.HandlerFunc {
+	return func(m telebot.Context) error {
+		chat := m.Chat()
+		if chat.Type != telebot.ChatGroup && chat.Type != telebot.ChatSuperGroup {
+			return m.Send("use /group in a group chat")
+		}
+
+		if args := m.Args(); len(args) > 0 {
+			if !h.isGroupAdmin(m) {
+				return m.Reply("only group admins may change settings")
+			}
+
+			if len(args) != 2 || h.gs.Set(chat.ID, args[0], args[1]) != nil {
+				return m.Reply("usage: /group mode <menu | auto | off>\n/group quality <p360 | p720 | p1080 | ... | mp3 | opus | m4a>")
+			}
+		}
+
+		settings := h.gs.Settings(chat.ID)
+
+		return m.Reply(fmt.Sprintf("mode: %s\nquality: %s", settings.Mode, settings.Quality))
+	}
+}
+
+func (h *TelegramMessageHandler) isGroupAdmin(m telebot.Context) bool {
+	if h.as.IsAdmin(m.Sender().ID) {
+		return true
+	}
+
+	member, err := m.Bot().ChatMemberOf(m.Chat(), m.Sender())
+	if err != nil {
+		log.Logger.Errorw("failed to get chat member", "chat", m.Chat().ID, "user", m.Sender().ID, "error", err)
+		return false
+	}
+
+	return member.Role == telebot.Administrator || member.Role == telebot.Creator
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/telegram"
+	"github.com/google/uuid"
+	"github.com/gotd/td/tg"
+	"gopkg.in/telebot.v3"
+)
+
+const (
+	inlineSearchResults = 5
+	inlineCacheTime     = 300
+
+	// minInlineQueryLen skips the first keystrokes, Telegram sends a query per keystroke
+	minInlineQueryLen = 3
+	// maxInlineQueries limits yt-dlp processes started by inline queries of all users
+	maxInlineQueries = 4
+)
+
+// inlineQuery is a query in progress, a newer query of the same user cancels it.
+type inlineQuery struct {
+	cancel context.CancelFunc
+}
+
+// InlineStatusButton is attached to the inline placeholder, since Telegram reports inline_message_id
+// of the chosen result only for messages with a keyboard.
+var InlineStatusButton = telebot.Btn{Unique: "inline_status"}
+
+// OnQuery answers inline queries with options of the pasted url or with search results.
+func (h *TelegramMessageHandler) OnQuery() telebot.HandlerFunc {
+	return func(m telebot.Context) (err error) {
+		query := strings.TrimSpace(m.Query().Text)
+		if len([]rune(query)) < minInlineQueryLen {
+			return nil
+		}
+
+		// users over quota may not start yt-dlp
+		if h.ls.Check(m.Sender().ID, 0, 0) != nil {
+			return nil
+		}
+
+		ctx, done := h.startInlineQuery(m.Sender().ID)
+		defer done()
+
+		select {
+		case h.inlineSem <- struct{}{}:
+			defer func() { <-h.inlineSem }()
+		case <-ctx.Done():
+			return nil
+		}
+
+		opts, err := h.inlineOptions(ctx, query)
+		if err != nil {
+			// the query is superseded by a newer one, the empty answer must not be cached
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			log.Logger.Debugw("inline query failed", "query", query, "error", err)
+			return answerNotCached(m)
+		}
+
+		results := make(telebot.Results, 0, len(opts))
+		for _, opt := range opts {
+			results = append(results, inlineResult(opt))
+		}
+
+		// results depend on the user access, so they are cached per user
+		return m.Answer(&telebot.QueryResponse{
+			Results:    results,
+			CacheTime:  inlineCacheTime,
+			IsPersonal: true,
+		})
+	}
+}
+
+// answerNotCached answers the query with no results, which Telegram must not cache, since the failure may be
+// transient. QueryResponse omits zero cache time, so Telegram default of 300 seconds would be used.
+func answerNotCached(m telebot.Context) error {
+	_, err := m.Bot().Raw("answerInlineQuery", map[string]any{
+		"inline_query_id": m.Query().ID,
+		"results":         []any{},
+		"cache_time":      0,
+		"is_personal":     true,
+	})
+
+	return err
+}
+
+// startInlineQuery cancels the previous query of the user, done must be called when the query is answered.
+func (h *TelegramMessageHandler) startInlineQuery(userID int64) (ctx context.Context, done func()) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	query := &inlineQuery{cancel: cancel}
+
+	h.inlineMu.Lock()
+	if prev, ok := h.inlineQueries[userID]; ok {
+		prev.cancel()
+	}
+	h.inlineQueries[userID] = query
+	h.inlineMu.Unlock()
+
+	return ctx, func() {
+		cancel()
+
+		h.inlineMu.Lock()
+		if h.inlineQueries[userID] == query {
+			delete(h.inlineQueries, userID)
+		}
+		h.inlineMu.Unlock()
+	}
+}
+
+// OnInlineResult starts download of the chosen inline result, the placeholder message is replaced with the file.
+// Inline feedback must be enabled with @BotFather /setinlinefeedback.
+func (h *TelegramMessageHandler) OnInlineResult(userbotClient *telegram.UserBotClient) telebot.HandlerFunc {
+	return func(m telebot.Context) (err error) {
+		result := m.InlineResult()
+		if len(result.MessageID) == 0 {
+			return nil
+		}
+
+		job := &models.Job{
+			ID:              uuid.New().String(),
+			UserID:          m.Sender().ID,
+			ChatID:          m.Sender().ID,
+			Username:        m.Sender().Username,
+			InlineMessageID: result.MessageID,
+			CreatedAt:       time.Now(),
+		}
+
+		status := jobStatusMessage(m.Bot(), job, cancelJobMarkup(job.ID))
+		defer func() {
+			if err != nil {
+				status.Finish(errorText(err))
+			}
+		}()
+
+		videoOption, ok := h.vs.getFromCache(result.ResultID)
+		if !ok {
+			return ErrNotFound
+		}
+		job.VideoOption = *applyUserSettings(h.ss.Get(job.UserID), videoOption)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+
+		if sent, err := h.sendCachedMedia(ctx, userbotClient, job); err != nil || sent {
+			return err
+		}
+
+		if err = h.ls.Check(job.UserID, 1, videoOption.Size); err != nil {
+			return err
+		}
+
+		status.Set("preparing download")
+
+		position, err := h.qs.Enqueue(job)
+		if err != nil {
+			return err
+		}
+
+		if err = h.ls.RecordDownloads(job.UserID, 1); err != nil {
+			log.Logger.Errorw("failed to record download", "user", job.UserID, "error", err)
+		}
+
+		if position > 0 {
+			status.Set(fmt.Sprintf("download queued, #%d in queue", position))
+		}
+
+		return nil
+	}
+}
+
+func (h *TelegramMessageHandler) OnInlineStatus() telebot.HandlerFunc {
+	return func(m telebot.Context) error {
+		return m.Respond(&telebot.CallbackResponse{Text: "download is being prepared"})
+	}
+}
+
+// deliverInline uploads the file without sending it to a chat and attaches it to the inline message.
+func (h *TelegramMessageHandler) deliverInline(ctx context.Context, userbotClient *telegram.UserBotClient, status *statusMessage, job *models.Job, path string, size uint64) error {
+	// inline message may hold a single file only
+	if h.vs.IsOversized(size) {
+		return ErrFileTooLarge
+	}
+
+	status.Set("uploading")
+
+	media, err := h.uploadFile(job, path, func() (*models.CachedMedia, error) {
+		return userbotClient.UploadMedia(ctx, &tg.InputPeerUser{UserID: job.UserID}, &job.VideoOption, path, status.UploadProgress)
+	})
+	if err != nil {
+		return err
+	}
+	if media == nil {
+		return ErrNotFound
+	}
+
+	h.vs.SaveCachedMedia(&job.VideoOption, media)
+
+	return userbotClient.EditInlineMessage(ctx, job.InlineMessageID, &job.VideoOption, media)
+}
+
+// inlineOptions returns options of the video or playlist url, any other query is searched.
+func (h *TelegramMessageHandler) inlineOptions(ctx context.Context, query string) ([]*models.VideoOption, error) {
+	videoURL, err := fetchFirstURL(query)
+	if err != nil {
+		playlist, err := h.vs.Search(ctx, query, inlineSearchResults)
+		if err != nil {
+			return nil, err
+		}
+
+		return h.vs.GetSearchOptions(playlist), nil
+	}
+
+	videoInfo, playlist, err := h.vs.GetVideoInfo(ctx, videoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if playlist != nil {
+		return h.vs.GetSearchOptions(playlist), nil
+	}
+
+	opts, err := h.vs.GetVideoOptions(videoInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.VideoOption, 0, len(opts))
+	for _, opt := range opts {
+		if !opt.Oversized {
+			result = append(result, opt)
+		}
+	}
+
+	return result, nil
+}
+
+func inlineResult(opt *models.VideoOption) telebot.Result {
+	emoji := videoEmoji
+	if opt.Audio {
+		emoji = audioEmoji
+	}
+
+	description := opt.Label
+	if opt.VideoInfo.Duration > 0 {
+		description += ", " + models.FormatTimestamp(opt.VideoInfo.Duration)
+	}
+	if opt.Size > 0 {
+		description += ", " + humanize.Bytes(opt.Size)
+	}
+
+	result := &telebot.ArticleResult{
+		Title:       emoji + " " + opt.VideoInfo.Title,
+		Description: description,
+		ThumbURL:    opt.VideoInfo.ThumbURL,
+	}
+	result.SetResultID(opt.ID)
+	result.SetContent(&telebot.InputTextMessageContent{
+		Text: "⏳ " + opt.Label + " - " + opt.VideoInfo.Title,
+	})
+
+	markup := &telebot.ReplyMarkup{}
+	markup.Inline(markup.Row(markup.Data("preparing download", InlineStatusButton.Unique)))
+	result.SetReplyMarkup(markup)
+
+	return result
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"gopkg.in/telebot.v3"
+)
+
+var (
+	SearchPageButton   = telebot.Btn{Unique: "search_page"}
+	SearchResultButton = telebot.Btn{Unique: "search_result"}
+)
+
+// sendSearchResults searches the text and sends the first page of results.
+func (h *TelegramMessageHandler) sendSearchResults(m telebot.Context, query string) error {
+	tmpMsg, err := m.Bot().Send(m.Sender(), "searching")
+	if err != nil {
+		return err
+	}
+	defer m.Bot().Delete(tmpMsg)
+
+	m.Notify(telebot.Typing)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	result, err := h.vs.SearchVideos(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	text, markup := createSearchMessage(result, 0, h.conf.Search.PageSize)
+	return m.Send(text, markup, telebot.NoPreview)
+}
+
+// OnSearchPage shows another page of search results, callback data is "<search ID>|<page>".
+func (h *TelegramMessageHandler) OnSearchPage() telebot.HandlerFunc {
+	return func(m telebot.Context) error {
+		result, page, ok := h.searchCallback(m)
+		if !ok {
+			return m.Respond(&telebot.CallbackResponse{Text: "search results are expired, send the query again"})
+		}
+
+		defer m.Respond()
+
+		text, markup := createSearchMessage(result, page, h.conf.Search.PageSize)
+
+		// the current page button does not change the message
+		err := m.Edit(text, markup, telebot.NoPreview)
+		if errors.Is(err, telebot.ErrSameMessageContent) || errors.Is(err, telebot.ErrMessageNotModified) {
+			return nil
+		}
+
+		return err
+	}
+}
+
+// OnSearchResult sends download options of the picked result, callback data is "<search ID>|<result index>".
+func (h *TelegramMessageHandler) OnSearchResult() telebot.HandlerFunc {
+	return func(m telebot.Context) (err error) {
+		result, i, ok := h.searchCallback(m)
+		if !ok || i >= len(result.Entries) {
+			return m.Respond(&telebot.CallbackResponse{Text: "search results are expired, send the query again"})
+		}
+
+		defer func() {
+			if err != nil {
+				defer m.Bot().Send(m.Sender(), errorText(err))
+			}
+		}()
+
+		defer m.Respond()
+
+		if err = h.ls.Check(m.Sender().ID, 0, 0); err != nil {
+			return err
+		}
+
+		tmpMsg, err := m.Bot().Send(m.Sender(), "gathering info")
+		if err != nil {
+			return err
+		}
+		defer m.Bot().Delete(tmpMsg)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+
+		return h.sendVideoInfo(ctx, m, result.Entries[i].URL, nil)
+	}
+}
+
+func (h *TelegramMessageHandler) searchCallback(m telebot.Context) (*models.SearchResult, int, bool) {
+	searchID, arg, ok := strings.Cut(m.Callback().Data, "|")
+	if !ok {
+		return nil, 0, false
+	}
+
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 0 {
+		return nil, 0, false
+	}
+
+	result, ok := h.vs.GetSearchResult(searchID)
+
+	return result, n, ok
+}
+
+func createSearchMessage(result *models.SearchResult, page, pageSize int) (string, *telebot.ReplyMarkup) {
+	pages := (len(result.Entries) + pageSize - 1) / pageSize
+	if page >= pages {
+		page = pages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+
+	start := page * pageSize
+	end := start + pageSize
+	if end > len(result.Entries) {
+		end = len(result.Entries)
+	}
+
+	lines := []string{fmt.Sprintf("search results for '%s':", result.Query), ""}
+
+	inlineMenu := &telebot.ReplyMarkup{}
+	rows := make([]telebot.Row, 0, pageSize+1)
+
+	for i := start; i < end; i++ {
+		entry := result.Entries[i]
+
+		line := fmt.Sprintf("%d. %s", i+1, entry.Title)
+		var details []string
+		if len(entry.Uploader) > 0 {
+			details = append(details, entry.Uploader)
+		}
+		if entry.Duration > 0 {
+			details = append(details, models.FormatTimestamp(entry.Duration))
+		}
+		if len(details) > 0 {
+			line += " (" + strings.Join(details, ", ") + ")"
+		}
+		lines = append(lines, line)
+
+		rows = append(rows, inlineMenu.Row(inlineMenu.Data(fmt.Sprintf("%d. %s", i+1, entry.Title), SearchResultButton.Unique, result.ID+"|"+strconv.Itoa(i))))
+	}
+
+	if pages > 1 {
+		var nav telebot.Row
+		if page > 0 {
+			nav = append(nav, inlineMenu.Data("◀️", SearchPageButton.Unique, result.ID+"|"+strconv.Itoa(page-1)))
+		}
+		nav = append(nav, inlineMenu.Data(fmt.Sprintf("%d / %d", page+1, pages), SearchPageButton.Unique, result.ID+"|"+strconv.Itoa(page)))
+		if page < pages-1 {
+			nav = append(nav, inlineMenu.Data("▶️", SearchPageButton.Unique, result.ID+"|"+strconv.Itoa(page+1)))
+		}
+		rows = append(rows, nav)
+	}
+
+	inlineMenu.Inline(rows...)
+
+	return strings.Join(lines, "\n"), inlineMenu
+}
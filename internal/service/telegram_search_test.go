@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/config"
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/far4599/telegram-bot-youtube-download/internal/repository"
+)
+
+func testSearchResult(n int) *models.SearchResult {
+	result := &models.SearchResult{ID: "s1", Query: "cats"}
+	for i := 0; i < n; i++ {
+		result.Entries = append(result.Entries, models.PlaylistEntry{
+			URL:      fmt.Sprintf("https://youtu.be/%d", i),
+			Title:    fmt.Sprintf("video %d", i+1),
+			Uploader: "uploader",
+			Duration: 83,
+		})
+	}
+
+	return result
+}
+
+func TestCreateSearchMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		entries   int
+		page      int
+		wantFirst string
+		wantLast  string
+		wantNav   []string
+	}{
+		{name: "single page", entries: 3, page: 0, wantFirst: "s1|0", wantLast: "s1|2"},
+		{name: "first page", entries: 12, page: 0, wantFirst: "s1|0", wantLast: "s1|4", wantNav: []string{"1 / 3", "▶️"}},
+		{name: "middle page", entries: 12, page: 1, wantFirst: "s1|5", wantLast: "s1|9", wantNav: []string{"◀️", "2 / 3", "▶️"}},
+		{name: "last page is short", entries: 12, page: 2, wantFirst: "s1|10", wantLast: "s1|11", wantNav: []string{"◀️", "3 / 3"}},
+		{name: "page out of range", entries: 12, page: 7, wantFirst: "s1|10", wantLast: "s1|11", wantNav: []string{"◀️", "3 / 3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, markup := createSearchMessage(testSearchResult(tt.entries), tt.page, 5)
+
+			if !strings.HasPrefix(text, "search results for 'cats':") {
+				t.Errorf("got text %q", text)
+			}
+
+			rows := markup.InlineKeyboard
+			if len(tt.wantNav) > 0 {
+				nav := rows[len(rows)-1]
+				rows = rows[:len(rows)-1]
+
+				var labels []string
+				for _, btn := range nav {
+					labels = append(labels, btn.Text)
+				}
+				if !equalIDs(labels, tt.wantNav) {
+					t.Errorf("got navigation %v, want %v", labels, tt.wantNav)
+				}
+			}
+
+			// result buttons carry the search ID and the index of the entry
+			first, last := rows[0][0], rows[len(rows)-1][0]
+			if first.Data != tt.wantFirst || last.Data != tt.wantLast {
+				t.Errorf("got buttons %q..%q, want %q..%q", first.Data, last.Data, tt.wantFirst, tt.wantLast)
+			}
+			if !strings.Contains(text, first.Text+" (uploader, 1:23)") {
+				t.Errorf("text %q has no line of %q", text, first.Text)
+			}
+		})
+	}
+}
+
+func TestCreateSearchMessageEmpty(t *testing.T) {
+	text, markup := createSearchMessage(testSearchResult(0), 0, 5)
+	if len(markup.InlineKeyboard) != 0 || !strings.HasPrefix(text, "search results") {
+		t.Errorf("got %q, %+v", text, markup.InlineKeyboard)
+	}
+}
+
+func TestSearchVideos(t *testing.T) {
+	conf := &config.Config{}
+	conf.Search.Provider = "ytsearch"
+	conf.Search.Results = 10
+	conf.Playlist.MaxEntries = 50
+
+	searches, err := repository.NewSearchRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dl := &fakeDownloader{probeOut: []byte(playlistJSON)}
+	s := &VideoService{conf: conf, dl: dl, searches: searches}
+
+	result, err := s.SearchVideos(context.Background(), "rick astley")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dl.probed) != 1 || dl.probed[0] != "ytsearch10:rick astley" {
+		t.Errorf("got probed %v", dl.probed)
+	}
+	if result.Query != "rick astley" || len(result.Entries) != 3 {
+		t.Errorf("got %+v", result)
+	}
+	if stored, ok := s.GetSearchResult(result.ID); !ok || stored != result {
+		t.Error("search result is not stored for pagination")
+	}
+
+	// a single video is not a search result
+	dl.probeOut = []byte(videoJSON)
+	if _, err = s.SearchVideos(context.Background(), "rick astley"); err != ErrVideoNotFound {
+		t.Errorf("got error %v, want %v", err, ErrVideoNotFound)
+	}
+}
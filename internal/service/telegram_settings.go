@@ -0,0 +1,73 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"gopkg.in/telebot.v3"
+)
+
+// SettingsButton switches the setting given in the data to its next value
+var SettingsButton = telebot.Btn{Unique: "settings"}
+
+// OnSettings shows settings of the user, a setting may also be changed with "/settings <key> <value>".
+func (h *TelegramMessageHandler) OnSettings() telebot.HandlerFunc {
+	return func(m telebot.Context) error {
+		if args := m.Args(); len(args) > 0 {
+			if len(args) != 2 || h.ss.Set(m.Sender().ID, args[0], args[1]) != nil {
+				return m.Send("usage: /settings <" + strings.Join(SettingKeys, " | ") + "> <value>")
+			}
+		}
+
+		msg, opts := createSettingsMessage(h.ss.Get(m.Sender().ID))
+		return m.Send(msg, opts...)
+	}
+}
+
+func (h *TelegramMessageHandler) OnSettingsButton() telebot.HandlerFunc {
+	return func(m telebot.Context) error {
+		defer m.Respond()
+
+		if err := h.ss.Next(m.Sender().ID, m.Callback().Data); err != nil {
+			return m.Send(errorText(err))
+		}
+
+		msg, opts := createSettingsMessage(h.ss.Get(m.Sender().ID))
+		return m.Edit(msg, opts...)
+	}
+}
+
+// applyUserSettings returns a copy of the option with the caption style of the user,
+// playlist audio is converted to the preferred codec.
+func applyUserSettings(settings models.UserSettings, videoOption *models.VideoOption) *models.VideoOption {
+	opt := *videoOption
+	opt.CaptionStyle = settings.CaptionStyle
+
+	if opt.Playlist != nil && opt.Audio {
+		codec := models.AudioCodecByName(settings.AudioCodec)
+		opt.AudioCodec = codec.Name
+		opt.Label = codec.Label
+	}
+
+	return &opt
+}
+
+func createSettingsMessage(settings models.UserSettings) (msg any, options []any) {
+	menu := &telebot.ReplyMarkup{}
+
+	var rows []telebot.Row
+	for _, key := range SettingKeys {
+		rows = append(rows, menu.Row(menu.Data(fmt.Sprintf("%s: %s", key, SettingValue(settings, key)), SettingsButton.Unique, key)))
+	}
+	menu.Inline(rows...)
+
+	msg = "Settings, tap to change:\n\n" +
+		"quality - quality downloaded right away in auto mode\n" +
+		"codec - audio codec of playlists and audio quality\n" +
+		"auto - download right away instead of the menu\n" +
+		"subs - offer video with embedded subtitles of the language\n" +
+		"caption - caption of sent videos"
+
+	return msg, []any{menu}
+}
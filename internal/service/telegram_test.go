@@ -0,0 +1,70 @@
+package service
+
+import (
+	"testing"
+
+	"gopkg.in/telebot.v3"
+)
+
+func TestExtractURLs(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  *telebot.Message
+		want []string
+	}{
+		{
+			name: "url entity",
+			msg: &telebot.Message{
+				Text:     "look youtu.be/x",
+				Entities: telebot.Entities{{Type: telebot.EntityURL, Offset: 5, Length: 10}},
+			},
+			want: []string{"https://youtu.be/x"},
+		},
+		{
+			name: "text link",
+			msg: &telebot.Message{
+				Text:     "this video",
+				Entities: telebot.Entities{{Type: telebot.EntityTextLink, Offset: 5, Length: 5, URL: "https://youtu.be/x"}},
+			},
+			want: []string{"https://youtu.be/x"},
+		},
+		{
+			name: "without entities",
+			msg:  &telebot.Message{Text: "https://youtu.be/x and https://youtu.be/y https://youtu.be/x"},
+			want: []string{"https://youtu.be/x", "https://youtu.be/y"},
+		},
+		{
+			name: "formatting entities only",
+			msg: &telebot.Message{
+				Text:     "wow https://youtu.be/x",
+				Entities: telebot.Entities{{Type: telebot.EntityBold, Offset: 0, Length: 3}},
+			},
+			want: []string{"https://youtu.be/x"},
+		},
+		{
+			name: "caption entity",
+			msg: &telebot.Message{
+				Caption:         "source: youtu.be/x",
+				CaptionEntities: telebot.Entities{{Type: telebot.EntityURL, Offset: 8, Length: 10}},
+			},
+			want: []string{"https://youtu.be/x"},
+		},
+		{
+			name: "caption without entities",
+			msg:  &telebot.Message{Caption: "source: https://youtu.be/x"},
+			want: []string{"https://youtu.be/x"},
+		},
+		{
+			name: "no links",
+			msg:  &telebot.Message{Text: "cats"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractURLs(tt.msg); !equalIDs(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
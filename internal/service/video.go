@@ -1,26 +1,24 @@
 package service
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	"fmt"
-	"io"
-	"os"
-	"os/exec"
 	"path"
+	"path/filepath"
 	"strconv"
-	"strings"
 	"sync"
+	"time"
 
 	"github.com/avast/retry-go/v4"
+	"github.com/far4599/telegram-bot-youtube-download/internal/config"
 	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/hash"
 	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/metrics"
 	"github.com/far4599/telegram-bot-youtube-download/internal/repository"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/valyala/fastjson"
-	"golang.org/x/sync/errgroup"
 )
 
 var (
@@ -30,27 +28,55 @@ var (
 
 	preferedAudioExt = []string{"m4a", "mp3", "webm"}
 	preferedVideoExt = []string{"mp4", "webm", "3gp"}
+
+	// mergedSizes are offered as separate video and audio streams merged after download
+	mergedSizes = []int{1080, 1440, 2160}
 )
 
 const (
-	cacheDir = "/tmp/yt-dlp"
-	tmpDir   = "/tmp"
+	tmpDir = "/tmp"
+
+	// the downloader version is checked by readiness probes, an error is rechecked sooner
+	downloaderVersionTTL      = 10 * time.Minute
+	downloaderVersionErrorTTL = 30 * time.Second
 )
 
 type VideoService struct {
-	maxRetry uint
-	repo     *repository.InMemRepository
+	conf *config.Config
+
+	dl         Downloader
+	repo       repository.Repository
+	mediaCache *repository.MediaCacheRepository
+	searches   *repository.SearchRepository
+
+	versionMu        sync.Mutex
+	version          string
+	versionErr       error
+	versionCheckedAt time.Time
 }
 
-func NewVideoService(maxRetry uint, repo *repository.InMemRepository) (*VideoService, error) {
+func NewVideoService(conf *config.Config, dl Downloader, repo repository.Repository, mediaCache *repository.MediaCacheRepository, searches *repository.SearchRepository) (*VideoService, error) {
 	return &VideoService{
-		maxRetry: maxRetry,
-		repo:     repo,
+		conf:       conf,
+		dl:         dl,
+		repo:       repo,
+		mediaCache: mediaCache,
+		searches:   searches,
 	}, nil
 }
 
-func (s *VideoService) GetVideoInfo(ctx context.Context, url string) (*models.VideoInfo, *fastjson.Value, error) {
-	out, err := readAll(s.runWithRetry(ctx, url, true, "--no-download"))
+// GetVideoInfo fetches info of the video. If url is a playlist, the playlist info is returned instead.
+func (s *VideoService) GetVideoInfo(ctx context.Context, url string) (*models.VideoInfo, *models.PlaylistInfo, error) {
+	start := time.Now()
+
+	videoInfo, playlist, err := s.getVideoInfo(ctx, url)
+	metrics.VideoInfoDuration.WithLabelValues(metrics.Result(err)).Observe(time.Since(start).Seconds())
+
+	return videoInfo, playlist, err
+}
+
+func (s *VideoService) getVideoInfo(ctx context.Context, url string) (*models.VideoInfo, *models.PlaylistInfo, error) {
+	out, err := s.dl.Probe(ctx, url)
 	if err != nil {
 		if !errors.Is(err, new(retry.Error)) {
 			return nil, nil, ErrInvalidURL
@@ -68,44 +94,160 @@ func (s *VideoService) GetVideoInfo(ctx context.Context, url string) (*models.Vi
 		return nil, nil, ErrVideoNotFound
 	}
 
-	return &models.VideoInfo{
-		URL:      url,
-		Title:    string(json.GetStringBytes("title")),
-		ThumbURL: string(json.GetStringBytes("thumbnail")),
-		Duration: json.GetInt("duration"),
-		Vertical: isVertical(json),
-		Youtube:  isYoutube(json),
-	}, json, nil
+	if isPlaylist(json) {
+		playlist, err := parsePlaylistInfo(url, json, s.conf.Playlist.MaxEntries)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return nil, playlist, nil
+	}
+
+	return parseVideoInfo(url, json), nil, nil
+}
+
+// DownloaderVersion returns the downloader version, an error means videos may not be downloaded.
+// The result is cached, so frequent probes do not start the downloader every time.
+func (s *VideoService) DownloaderVersion(ctx context.Context) (string, error) {
+	s.versionMu.Lock()
+	defer s.versionMu.Unlock()
+
+	ttl := downloaderVersionTTL
+	if s.versionErr != nil {
+		ttl = downloaderVersionErrorTTL
+	}
+
+	if s.versionCheckedAt.IsZero() || time.Since(s.versionCheckedAt) > ttl {
+		version, err := s.dl.Version(ctx)
+		// a probe cancelled by its caller says nothing about the downloader
+		if ctx.Err() != nil {
+			return "", err
+		}
+
+		s.version, s.versionErr, s.versionCheckedAt = version, err, time.Now()
+	}
+
+	return s.version, s.versionErr
 }
 
-func (s *VideoService) GetVideoOptions(videoInfo *models.VideoInfo, json *fastjson.Value) ([]*models.VideoOption, error) {
+func (s *VideoService) GetVideoOptions(videoInfo *models.VideoInfo) ([]*models.VideoOption, error) {
 	result := make([]*models.VideoOption, 0, 4)
 
 	if videoInfo.Youtube {
-		opt, err := s.getVideoOption(json, -1)
+		opt, err := s.getVideoOption(videoInfo, -1)
 		if err == nil {
-			opt.VideoInfo = *videoInfo
-			s.saveToCache(opt)
+			for _, codec := range models.AudioCodecs {
+				codecOpt := *opt
+				codecOpt.AudioCodec = codec.Name
+				codecOpt.Label = codec.Label
+				codecOpt.VideoInfo = *videoInfo
+
+				switch codec {
+				case models.AudioCodecMP3:
+					codecOpt.Size = uint64(videoInfo.Duration) * 320_000 / 8
+				case models.AudioCodecOpus:
+					codecOpt.FormatID = "bestaudio[acodec=opus]/" + opt.FormatID
+				}
+				codecOpt.Oversized = s.IsOversized(codecOpt.Size)
 
-			result = append(result, opt)
+				result = append(result, &codecOpt)
+			}
 		}
 	}
 
+	labels := make(map[string]bool)
+
 	sizes := []int{300, 600, 1000}
 	for _, size := range sizes {
-		opt, err := s.getVideoOption(json, size)
+		opt, err := s.getVideoOption(videoInfo, size)
 		if err == nil {
 			opt.VideoInfo = *videoInfo
-			s.saveToCache(opt)
 
+			labels[opt.Label] = true
 			result = append(result, opt)
 		}
 	}
 
+	for _, size := range mergedSizes {
+		opt, err := s.getMergedVideoOption(videoInfo, size)
+		// smaller sizes may select the same stream
+		if err == nil && !labels[opt.Label] {
+			opt.VideoInfo = *videoInfo
+
+			labels[opt.Label] = true
+			result = append(result, opt)
+		}
+	}
+	s.saveToCache(result...)
+
 	return result, nil
 }
 
-func (s *VideoService) getVideoOption(json *fastjson.Value, size int) (*models.VideoOption, error) {
+// getMergedVideoOption selects the largest video only stream not exceeding the given size and the best audio stream,
+// which are merged by yt-dlp into a single file. The label is the actual size of the stream.
+func (s *VideoService) getMergedVideoOption(videoInfo *models.VideoInfo, size int) (*models.VideoOption, error) {
+	var video, audio *models.Format
+	for i := range videoInfo.Formats {
+		format := &videoInfo.Formats[i]
+
+		switch {
+		case format.AudioOnly():
+			if audio == nil || betterFormat(format, audio, preferedAudioExt, format.ABR, audio.ABR) {
+				audio = format
+			}
+		case format.VideoOnly():
+			dim := formatDim(format, videoInfo.Vertical)
+			if dim == 0 || dim > size {
+				continue
+			}
+			if video == nil {
+				video = format
+				continue
+			}
+
+			videoDim := formatDim(video, videoInfo.Vertical)
+			if dim > videoDim || dim == videoDim && betterFormat(format, video, preferedVideoExt, format.TBR, video.TBR) {
+				video = format
+			}
+		}
+	}
+
+	if video == nil || audio == nil {
+		return nil, ErrNotFound
+	}
+
+	fileSize := video.Filesize + audio.Filesize
+
+	return &models.VideoOption{
+		FormatID:  video.ID + "+" + audio.ID,
+		Label:     getLabel(video, false, videoInfo.Vertical),
+		Size:      fileSize,
+		Oversized: s.IsOversized(fileSize),
+	}, nil
+}
+
+// betterFormat compares formats by preferred extension first and by bitrate then.
+func betterFormat(a, b *models.Format, preferedExt []string, aBitrate, bBitrate float64) bool {
+	aRank := extRank(a.Ext, preferedExt)
+	bRank := extRank(b.Ext, preferedExt)
+	if aRank != bRank {
+		return aRank < bRank
+	}
+
+	return aBitrate > bBitrate
+}
+
+func extRank(ext string, preferedExt []string) int {
+	for i, e := range preferedExt {
+		if e == ext {
+			return i
+		}
+	}
+
+	return len(preferedExt)
+}
+
+func (s *VideoService) getVideoOption(videoInfo *models.VideoInfo, size int) (*models.VideoOption, error) {
 	extFilter := preferedVideoExt
 	var audio bool
 	if size == -1 {
@@ -113,18 +255,13 @@ func (s *VideoService) getVideoOption(json *fastjson.Value, size int) (*models.V
 		audio = true
 	}
 
-	found := make(map[string][]*fastjson.Value)
-
-	formats := json.GetArray("formats")
-	for _, format := range formats {
-		ext := string(format.GetStringBytes("ext"))
-
-		found[ext] = append(found[ext], format)
+	found := make(map[string][]*models.Format)
+	for i := range videoInfo.Formats {
+		format := &videoInfo.Formats[i]
+		found[format.Ext] = append(found[format.Ext], format)
 	}
 
-	vertical := isVertical(json)
-
-	var selected *fastjson.Value
+	var selected *models.Format
 	for _, ext := range extFilter {
 		if selected != nil {
 			break
@@ -136,28 +273,14 @@ func (s *VideoService) getVideoOption(json *fastjson.Value, size int) (*models.V
 				continue
 			}
 
-			withAudio := format.GetInt("abr") > 0 || format.GetInt("asr") > 0 || string(format.GetStringBytes("acodec")) != "none" || string(format.GetStringBytes("audio_ext")) != "none"
-
-			dimSize := strings.Split(string(format.GetStringBytes("resolution")), "x")
-			if len(dimSize) != 2 {
+			// formats without resolution are audio only
+			if format.Width == 0 || format.Height == 0 {
 				continue
 			}
 
-			dim := 1
-			if vertical {
-				dim = 0
-			}
-
-			i, err := strconv.Atoi(dimSize[dim])
-			if err != nil {
-				return nil, err
-			}
-
-			if i >= size {
-				if withAudio {
-					selected = format
-					break
-				}
+			if formatDim(format, videoInfo.Vertical) >= size && format.HasAudio() {
+				selected = format
+				break
 			}
 		}
 	}
@@ -167,68 +290,32 @@ func (s *VideoService) getVideoOption(json *fastjson.Value, size int) (*models.V
 	}
 
 	return &models.VideoOption{
-		FormatID: getFormatID(selected),
-		Label:    getLabel(selected, audio, vertical),
-		Size:     getFilesize(selected),
-		Audio:    audio,
+		FormatID:  selected.ID,
+		Label:     getLabel(selected, audio, videoInfo.Vertical),
+		Size:      selected.Filesize,
+		Oversized: s.IsOversized(selected.Filesize),
+		Audio:     audio,
 	}, nil
 }
 
-func (s *VideoService) DownloadVideo(ctx context.Context, videoOption *models.VideoOption) (string, error) {
-	args := []string{
-		"-o", "-",
-		"-f", videoOption.FormatID,
-		"--no-progress",
-	}
+func (s *VideoService) DownloadVideo(ctx context.Context, videoOption *models.VideoOption, onProgress ProgressFunc) (string, error) {
+	// a unique name, since the same option may be downloaded by several jobs at once
+	baseName := uuid.New().String()
 
-	resp, err := s.runWithRetry(ctx, videoOption.VideoInfo.URL, false, args...)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Close()
-
-	fileName := videoOption.ID + ".mp4"
+	fileName := baseName + ".mp4"
 	if videoOption.Audio {
-		fileName = videoOption.ID + ".mp3"
+		fileName = baseName + "." + models.AudioCodecByName(videoOption.AudioCodec).Ext
 	}
 	filePath := path.Join(tmpDir, fileName)
 
-	errGroup, errCtx := errgroup.WithContext(ctx)
-
-	errGroup.Go(func() error {
-		select {
-		case <-errCtx.Done():
-			return nil
-		case <-resp.closeCh:
-			return nil
-		case dlpErr, ok := <-resp.errCh:
-			if !ok {
-				return nil
-			}
-			return dlpErr
-		}
-	})
-
-	errGroup.Go(func() error {
-		f, errG := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY, 0744)
-		if errG != nil {
-			return errG
-		}
-		defer f.Close()
-
-		_, errG = io.Copy(f, resp.out)
-		if errG != nil {
-			return errG
-		}
-
-		resp.Close()
-
-		return nil
-	})
-
-	err = errGroup.Wait()
+	err := s.dl.Fetch(ctx, videoOption, filePath, onProgress)
+	if err == nil && videoOption.Subtitle != nil {
+		filePath, err = s.applySubtitles(ctx, videoOption, filePath)
+	}
 	if err != nil {
-		defer os.Remove(filePath)
+		// remove partially downloaded streams and subtitles as well
+		partials, _ := filepath.Glob(path.Join(tmpDir, baseName+"*"))
+		removeFiles(partials)
 
 		return "", err
 	}
@@ -236,200 +323,102 @@ func (s *VideoService) DownloadVideo(ctx context.Context, videoOption *models.Vi
 	return filePath, nil
 }
 
-func (s *VideoService) runWithRetry(ctx context.Context, url string, isJson bool, args ...string) (result *dlpResponse, err error) {
-	err = retry.Do(
-		func() error {
-			res, errR := runYtDlp(ctx, url, isJson, args...)
-			if errR != nil {
-				return errR
-			}
-
-			result = res
-
-			return nil
-		},
-		retry.Context(ctx),
-		retry.Attempts(s.maxRetry),
-	)
+// formatDim returns the smaller side of the video, which is used as a quality label.
+func formatDim(format *models.Format, vertical bool) int {
+	if vertical {
+		return format.Width
+	}
 
-	return
+	return format.Height
 }
 
-func runYtDlp(ctx context.Context, url string, isJson bool, args ...string) (*dlpResponse, error) {
-	defaultArgs := []string{
-		"-q", "-v",
-		"--ignore-errors",
-		"--no-call-home",
-		"--geo-bypass",
-		"--cache-dir", cacheDir,
-		// provide URL via stdin for security, youtube-dl has some run command args
-		"--batch-file", "-",
-	}
-
-	if isJson {
-		defaultArgs = append(defaultArgs, "-j")
+func getLabel(format *models.Format, audio, vertical bool) string {
+	if audio {
+		return "only audio"
 	}
 
-	args = append(defaultArgs, args...)
-
-	log.Logger.Infow("yt-dlp arguments", "args", args, "url", url)
-
-	cmd := exec.CommandContext(
-		ctx,
-		"yt-dlp",
-		args...,
-	)
-
-	cmd.Stdin = bytes.NewBufferString(url + "\n")
+	return "p" + strconv.Itoa(formatDim(format, vertical))
+}
 
-	out, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, err
+// GetCachedMedia returns a reference to the file previously uploaded for the same video and format.
+func (s *VideoService) GetCachedMedia(opt *models.VideoOption) (*models.CachedMedia, bool) {
+	key, ok := mediaCacheKey(opt)
+	if !ok {
+		return nil, false
 	}
 
-	errOut, err := cmd.StderrPipe()
+	media, found, err := s.mediaCache.Get(key)
 	if err != nil {
-		return nil, err
-	}
-
-	errCh := make(chan error, 10)
-
-	go func() {
-		const errorPrefix = "ERROR: "
-		stderrLineScanner := bufio.NewScanner(errOut)
-		for stderrLineScanner.Scan() {
-			line := stderrLineScanner.Text()
-			if strings.HasPrefix(line, errorPrefix) {
-				log.Logger.Errorw("yt-dlp returned error", "error", line)
-				errCh <- dlpError(line)
-			} else {
-				log.Logger.Debug(line)
-			}
-		}
-	}()
-
-	if err = cmd.Start(); err != nil {
-		return nil, err
+		log.Logger.Errorw("failed to get cached media", "key", key, "error", err)
+		return nil, false
 	}
+	metrics.CacheRequests.WithLabelValues("media", metrics.CacheResult(found)).Inc()
 
-	closeCh := make(chan struct{})
-
-	go func() {
-		<-closeCh
-
-		cmd.Wait()
-	}()
-
-	return &dlpResponse{
-		out:     out,
-		errCh:   errCh,
-		closeCh: closeCh,
-	}, nil
+	return media, found
 }
 
-func readAll(resp *dlpResponse, err error) ([]byte, error) {
-	if err != nil {
-		return nil, err
+func (s *VideoService) SaveCachedMedia(opt *models.VideoOption, media *models.CachedMedia) {
+	key, ok := mediaCacheKey(opt)
+	if !ok || media == nil {
+		return
 	}
-	defer resp.Close()
 
-	return io.ReadAll(resp.out)
+	if err := s.mediaCache.Put(key, media); err != nil {
+		log.Logger.Errorw("failed to save cached media", "key", key, "error", err)
+	}
 }
 
-func isVertical(v *fastjson.Value) bool {
-	w, err := v.Get("width").Int64()
-	if err != nil {
-		panic(err)
+func (s *VideoService) DeleteCachedMedia(opt *models.VideoOption) {
+	key, ok := mediaCacheKey(opt)
+	if !ok {
+		return
 	}
 
-	h, err := v.Get("height").Int64()
-	if err != nil {
-		panic(err)
+	if err := s.mediaCache.Delete(key); err != nil {
+		log.Logger.Errorw("failed to delete cached media", "key", key, "error", err)
 	}
-
-	return h > w
 }
 
-func isYoutube(v *fastjson.Value) bool {
-	extractor := v.Get("extractor").String()
-	return strings.Contains(extractor, "youtube")
-}
-
-func getFormatID(v *fastjson.Value) string {
-	return strings.Trim(v.Get("format_id").String(), `"`)
-}
-
-func getLabel(v *fastjson.Value, audio, vertical bool) string {
-	if audio {
-		return "only audio"
+func mediaCacheKey(opt *models.VideoOption) (string, bool) {
+	if len(opt.VideoInfo.ID) == 0 || len(opt.VideoInfo.Extractor) == 0 {
+		return "", false
 	}
 
-	dim := "height"
-	if vertical {
-		dim = "width"
+	key := opt.VideoInfo.Extractor + ":" + opt.VideoInfo.ID + ":" + opt.FormatID
+	if opt.Audio {
+		key += ":" + opt.AudioCodec
 	}
-
-	return "p" + v.Get(dim).String()
-}
-
-func getFilesize(v *fastjson.Value) uint64 {
-	size := int64(0)
-	if v.Get("filesize") != nil {
-		size, _ = v.Get("filesize").Int64()
+	if opt.Subtitle != nil {
+		key += fmt.Sprintf(":%s:%t:%s", opt.Subtitle.Lang, opt.Subtitle.Auto, opt.SubtitleMode)
 	}
-	if v.Get("filesize_approx") != nil {
-		size, _ = v.Get("filesize_approx").Int64()
+	if opt.Clip != nil {
+		key += ":" + opt.Clip.String()
+	}
+	if opt.SplitChapters {
+		key += ":chapters"
 	}
 
-	return uint64(size)
-}
-
-func (s *VideoService) saveToCache(opt *models.VideoOption) {
-	opt.ID = uuid.New().String()
-
-	s.repo.Add(opt.ID, opt)
+	return hash.Sha256(key), true
 }
 
-func (s *VideoService) getFromCache(id string) (*models.VideoOption, bool) {
-	cachedOption, ok := s.repo.Get(id)
-	if !ok {
-		return nil, false
+// saveToCache assigns IDs to the options and stores them at once.
+func (s *VideoService) saveToCache(opts ...*models.VideoOption) {
+	for _, opt := range opts {
+		opt.ID = uuid.New().String()
 	}
 
-	videoOption, ok := cachedOption.(*models.VideoOption)
-	if !ok {
-		defer s.repo.Remove(id)
-		return nil, false
+	if err := s.repo.Put(opts...); err != nil {
+		log.Logger.Errorw("failed to save video options", "count", len(opts), "error", err)
 	}
-
-	return videoOption, true
-}
-
-type dlpResponse struct {
-	out     io.ReadCloser
-	errCh   chan error
-	closeCh chan struct{}
-
-	closeMu sync.Mutex
-	closed  bool
 }
 
-func (r *dlpResponse) Close() {
-	r.closeMu.Lock()
-	defer r.closeMu.Unlock()
-
-	if r.closed {
-		return
+func (s *VideoService) getFromCache(id string) (*models.VideoOption, bool) {
+	videoOption, ok, err := s.repo.Get(id)
+	if err != nil {
+		log.Logger.Errorw("failed to get video option", "id", id, "error", err)
+		return nil, false
 	}
+	metrics.CacheRequests.WithLabelValues("options", metrics.CacheResult(ok)).Inc()
 
-	defer close(r.closeCh)
-	defer r.out.Close()
-
-	r.closed = true
-}
-
-type dlpError string
-
-func (e dlpError) Error() string {
-	return string(e)
+	return videoOption, ok
 }
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/valyala/fastjson"
+)
+
+const uploadDateLayout = "20060102"
+
+func parseVideoInfo(url string, json *fastjson.Value) *models.VideoInfo {
+	info := &models.VideoInfo{
+		ID:          string(json.GetStringBytes("id")),
+		Extractor:   string(json.GetStringBytes("extractor_key")),
+		URL:         url,
+		WebpageURL:  string(json.GetStringBytes("webpage_url")),
+		Title:       string(json.GetStringBytes("title")),
+		ThumbURL:    string(json.GetStringBytes("thumbnail")),
+		Artist:      firstString(json, "artist", "creator", "uploader", "channel"),
+		Track:       firstString(json, "track", "title"),
+		Album:       string(json.GetStringBytes("album")),
+		Uploader:    string(json.GetStringBytes("uploader")),
+		UploaderID:  string(json.GetStringBytes("uploader_id")),
+		Channel:     string(json.GetStringBytes("channel")),
+		ChannelID:   string(json.GetStringBytes("channel_id")),
+		ViewCount:   json.GetInt64("view_count"),
+		LikeCount:   json.GetInt64("like_count"),
+		Description: string(json.GetStringBytes("description")),
+		Duration:    int(json.GetFloat64("duration")),
+		Width:       json.GetInt("width"),
+		Height:      json.GetInt("height"),
+		Youtube:     strings.Contains(strings.ToLower(string(json.GetStringBytes("extractor"))), "youtube"),
+	}
+
+	info.Vertical = info.Height > info.Width
+
+	if uploadDate, err := time.Parse(uploadDateLayout, string(json.GetStringBytes("upload_date"))); err == nil {
+		info.UploadDate = uploadDate
+	}
+
+	for _, chapter := range json.GetArray("chapters") {
+		info.Chapters = append(info.Chapters, models.Chapter{
+			Title:     string(chapter.GetStringBytes("title")),
+			StartTime: chapter.GetFloat64("start_time"),
+			EndTime:   chapter.GetFloat64("end_time"),
+		})
+	}
+
+	info.Subtitles = parseSubtitles(json.GetObject("subtitles"))
+	info.AutomaticCaptions = parseSubtitles(json.GetObject("automatic_captions"))
+
+	for _, format := range json.GetArray("formats") {
+		info.Formats = append(info.Formats, parseFormat(format))
+	}
+
+	return info
+}
+
+func parseFormat(v *fastjson.Value) models.Format {
+	filesize := v.GetFloat64("filesize")
+	if filesize == 0 {
+		filesize = v.GetFloat64("filesize_approx")
+	}
+
+	return models.Format{
+		ID:         string(v.GetStringBytes("format_id")),
+		Ext:        string(v.GetStringBytes("ext")),
+		Resolution: string(v.GetStringBytes("resolution")),
+		Width:      v.GetInt("width"),
+		Height:     v.GetInt("height"),
+		VCodec:     string(v.GetStringBytes("vcodec")),
+		ACodec:     string(v.GetStringBytes("acodec")),
+		AudioExt:   string(v.GetStringBytes("audio_ext")),
+		ABR:        v.GetFloat64("abr"),
+		ASR:        v.GetInt("asr"),
+		TBR:        v.GetFloat64("tbr"),
+		Filesize:   uint64(filesize),
+	}
+}
+
+func parseSubtitles(obj *fastjson.Object) map[string][]models.Subtitle {
+	if obj == nil {
+		return nil
+	}
+
+	result := make(map[string][]models.Subtitle, obj.Len())
+	obj.Visit(func(lang []byte, tracks *fastjson.Value) {
+		for _, track := range tracks.GetArray() {
+			result[string(lang)] = append(result[string(lang)], models.Subtitle{
+				Ext:  string(track.GetStringBytes("ext")),
+				URL:  string(track.GetStringBytes("url")),
+				Name: string(track.GetStringBytes("name")),
+			})
+		}
+	})
+
+	return result
+}
+
+func parsePlaylistInfo(url string, json *fastjson.Value, maxEntries int) (*models.PlaylistInfo, error) {
+	playlist := &models.PlaylistInfo{
+		ID:    string(json.GetStringBytes("id")),
+		URL:   url,
+		Title: string(json.GetStringBytes("title")),
+	}
+
+	for _, entry := range json.GetArray("entries") {
+		entryURL := firstString(entry, "url", "webpage_url")
+		if len(entryURL) == 0 {
+			continue
+		}
+
+		playlist.Total++
+		if len(playlist.Entries) >= maxEntries {
+			continue
+		}
+
+		playlist.Entries = append(playlist.Entries, models.PlaylistEntry{
+			ID:        string(entry.GetStringBytes("id")),
+			Extractor: string(entry.GetStringBytes("ie_key")),
+			URL:       entryURL,
+			Title:     string(entry.GetStringBytes("title")),
+			Uploader:  firstString(entry, "uploader", "channel"),
+			ThumbURL:  entryThumbnail(entry),
+			Duration:  int(entry.GetFloat64("duration")),
+		})
+	}
+
+	if len(playlist.Entries) == 0 {
+		return nil, ErrVideoNotFound
+	}
+
+	return playlist, nil
+}
+
+// entryThumbnail returns the last thumbnail of the flat playlist entry, yt-dlp sorts thumbnails by quality.
+func entryThumbnail(entry *fastjson.Value) string {
+	if thumb := string(entry.GetStringBytes("thumbnail")); len(thumb) > 0 {
+		return thumb
+	}
+
+	thumbs := entry.GetArray("thumbnails")
+	if len(thumbs) == 0 {
+		return ""
+	}
+
+	return string(thumbs[len(thumbs)-1].GetStringBytes("url"))
+}
+
+func isPlaylist(json *fastjson.Value) bool {
+	return string(json.GetStringBytes("_type")) == "playlist"
+}
+
+func firstString(v *fastjson.Value, keys ...string) string {
+	for _, key := range keys {
+		if s := string(v.GetStringBytes(key)); len(s) > 0 {
+			return s
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/valyala/fastjson"
+)
+
+func TestParseVideoInfo(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		test func(t *testing.T, json string)
+	}{
+		{
+			name: "metadata",
+			json: `{"id": "x", "extractor": "Youtube", "extractor_key": "Youtube", "title": "Song", "duration": 61.7,
+				"width": 1280, "height": 720, "uploader": "Uploader", "upload_date": "20230102", "view_count": 10}`,
+			test: func(t *testing.T, json string) {
+				info := parseTestVideoInfo(t, json)
+				if info.ID != "x" || info.Extractor != "Youtube" || !info.Youtube || info.Duration != 61 || info.ViewCount != 10 {
+					t.Errorf("got %+v", info)
+				}
+				if info.Vertical {
+					t.Error("landscape video is vertical")
+				}
+				if !info.UploadDate.Equal(time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)) {
+					t.Errorf("got upload date %s", info.UploadDate)
+				}
+				// artist and track fall back to uploader and title
+				if info.Artist != "Uploader" || info.Track != "Song" {
+					t.Errorf("got artist %q, track %q", info.Artist, info.Track)
+				}
+			},
+		},
+		{
+			name: "vertical video without upload date",
+			json: `{"id": "x", "extractor": "TikTok", "width": 1080, "height": 1920, "artist": "Artist", "track": "Track"}`,
+			test: func(t *testing.T, json string) {
+				info := parseTestVideoInfo(t, json)
+				if !info.Vertical || info.Youtube || !info.UploadDate.IsZero() {
+					t.Errorf("got %+v", info)
+				}
+				if info.Artist != "Artist" || info.Track != "Track" {
+					t.Errorf("got artist %q, track %q", info.Artist, info.Track)
+				}
+			},
+		},
+		{
+			name: "formats",
+			json: `{"formats": [
+				{"format_id": "137", "ext": "mp4", "width": 1920, "height": 1080, "vcodec": "avc1", "acodec": "none", "filesize": 100},
+				{"format_id": "251", "ext": "webm", "vcodec": "none", "acodec": "opus", "abr": 160, "filesize_approx": 50}
+			]}`,
+			test: func(t *testing.T, json string) {
+				info := parseTestVideoInfo(t, json)
+				if len(info.Formats) != 2 {
+					t.Fatalf("got %d formats", len(info.Formats))
+				}
+				if f := info.Formats[0]; f.ID != "137" || f.Height != 1080 || f.Filesize != 100 || !f.VideoOnly() {
+					t.Errorf("got %+v", f)
+				}
+				// approximate size is used without exact one
+				if f := info.Formats[1]; f.ID != "251" || f.Filesize != 50 || f.ABR != 160 || !f.AudioOnly() {
+					t.Errorf("got %+v", f)
+				}
+			},
+		},
+		{
+			name: "subtitles and chapters",
+			json: `{"subtitles": {"en": [{"ext": "vtt", "url": "https://example.com/en.vtt", "name": "English"}]},
+				"automatic_captions": {"de-orig": [{"ext": "vtt"}, {"ext": "srt"}]},
+				"chapters": [{"title": "Intro", "start_time": 0, "end_time": 12.5}]}`,
+			test: func(t *testing.T, json string) {
+				info := parseTestVideoInfo(t, json)
+				if subs := info.Subtitles["en"]; len(subs) != 1 || subs[0].Name != "English" || subs[0].Ext != "vtt" {
+					t.Errorf("got subtitles %+v", info.Subtitles)
+				}
+				if len(info.AutomaticCaptions["de-orig"]) != 2 {
+					t.Errorf("got captions %+v", info.AutomaticCaptions)
+				}
+				if len(info.Chapters) != 1 || info.Chapters[0].Title != "Intro" || info.Chapters[0].EndTime != 12.5 {
+					t.Errorf("got chapters %+v", info.Chapters)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, tt.json)
+		})
+	}
+}
+
+func TestParsePlaylistInfo(t *testing.T) {
+	tests := []struct {
+		name       string
+		json       string
+		maxEntries int
+		wantErr    error
+		wantURLs   []string
+		wantTotal  int
+		wantThumb  string
+	}{
+		{
+			name:       "entries",
+			json:       `{"id": "PL1", "title": "List", "entries": [{"url": "https://a", "thumbnails": [{"url": "small"}, {"url": "large"}]}, {"webpage_url": "https://b"}]}`,
+			maxEntries: 10,
+			wantURLs:   []string{"https://a", "https://b"},
+			wantTotal:  2,
+			wantThumb:  "large",
+		},
+		{
+			name:       "entries over limit are counted",
+			json:       `{"entries": [{"url": "https://a", "thumbnail": "thumb"}, {"url": "https://b"}, {"url": "https://c"}]}`,
+			maxEntries: 2,
+			wantURLs:   []string{"https://a", "https://b"},
+			wantTotal:  3,
+			wantThumb:  "thumb",
+		},
+		{
+			name:       "entries without url are skipped",
+			json:       `{"entries": [{"id": "private"}, {"url": "https://b"}]}`,
+			maxEntries: 10,
+			wantURLs:   []string{"https://b"},
+			wantTotal:  1,
+		},
+		{
+			name:       "no entries",
+			json:       `{"entries": [{"id": "private"}]}`,
+			maxEntries: 10,
+			wantErr:    ErrVideoNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			playlist, err := parsePlaylistInfo("https://list", fastjson.MustParse(tt.json), tt.maxEntries)
+			if err != tt.wantErr {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			var urls []string
+			for _, entry := range playlist.Entries {
+				urls = append(urls, entry.URL)
+			}
+			if !equalIDs(urls, tt.wantURLs) || playlist.Total != tt.wantTotal {
+				t.Errorf("got %v of %d entries, want %v of %d", urls, playlist.Total, tt.wantURLs, tt.wantTotal)
+			}
+			if playlist.Entries[0].ThumbURL != tt.wantThumb {
+				t.Errorf("got thumbnail %q, want %q", playlist.Entries[0].ThumbURL, tt.wantThumb)
+			}
+		})
+	}
+}
+
+func parseTestVideoInfo(t *testing.T, json string) *models.VideoInfo {
+	t.Helper()
+
+	v, err := fastjson.Parse(json)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return parseVideoInfo("https://example.com/video", v)
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/far4599/telegram-bot-youtube-download/internal/config"
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+)
+
+func TestDownloaderVersionCached(t *testing.T) {
+	dl := &fakeDownloader{version: "2023.07.06"}
+	s := &VideoService{dl: dl}
+
+	for i := 0; i < 3; i++ {
+		version, err := s.DownloaderVersion(context.Background())
+		if err != nil || version != dl.version {
+			t.Fatalf("got %q, %v", version, err)
+		}
+	}
+	if dl.versionCalls != 1 {
+		t.Errorf("downloader called %d times, want 1", dl.versionCalls)
+	}
+}
+
+func TestDownloaderVersionCancelledNotCached(t *testing.T) {
+	dl := &fakeDownloader{versionErr: errors.New("killed")}
+	s := &VideoService{dl: dl}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.DownloaderVersion(ctx); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	dl.version, dl.versionErr = "2023.07.06", nil
+	if version, err := s.DownloaderVersion(context.Background()); err != nil || version != dl.version {
+		t.Errorf("got %q, %v", version, err)
+	}
+}
+
+const (
+	videoJSON = `{
+		"id": "dQw4w9WgXcQ",
+		"extractor": "youtube",
+		"extractor_key": "Youtube",
+		"title": "Never Gonna Give You Up",
+		"duration": 212.0,
+		"width": 1920,
+		"height": 1080,
+		"upload_date": "20091025",
+		"formats": [
+			{"format_id": "18", "ext": "mp4", "width": 640, "height": 360, "vcodec": "avc1", "acodec": "mp4a", "filesize": 1000},
+			{"format_id": "140", "ext": "m4a", "vcodec": "none", "acodec": "mp4a", "abr": 129.5}
+		]
+	}`
+
+	playlistJSON = `{
+		"_type": "playlist",
+		"id": "PL123",
+		"title": "Mix",
+		"entries": [
+			{"id": "a", "ie_key": "Youtube", "url": "https://www.youtube.com/watch?v=a", "title": "A", "duration": 60},
+			{"id": "b", "ie_key": "Youtube", "title": "no url"},
+			{"id": "c", "ie_key": "Youtube", "url": "https://www.youtube.com/watch?v=c", "title": "C"},
+			{"id": "d", "ie_key": "Youtube", "url": "https://www.youtube.com/watch?v=d", "title": "D"}
+		]
+	}`
+)
+
+func TestGetVideoInfo(t *testing.T) {
+	conf := &config.Config{}
+	conf.Playlist.MaxEntries = 2
+
+	tests := []struct {
+		name        string
+		dl          *fakeDownloader
+		wantErr     error
+		wantVideo   string
+		wantEntries int
+		wantTotal   int
+	}{
+		{name: "video", dl: &fakeDownloader{probeOut: []byte(videoJSON)}, wantVideo: "dQw4w9WgXcQ"},
+		{name: "playlist", dl: &fakeDownloader{probeOut: []byte(playlistJSON)}, wantEntries: 2, wantTotal: 3},
+		{name: "empty playlist", dl: &fakeDownloader{probeOut: []byte(`{"_type": "playlist", "entries": []}`)}, wantErr: ErrVideoNotFound},
+		{name: "empty output", dl: &fakeDownloader{}, wantErr: ErrVideoNotFound},
+		{name: "invalid output", dl: &fakeDownloader{probeOut: []byte("ERROR: unsupported URL")}, wantErr: ErrVideoNotFound},
+		{name: "probe error", dl: &fakeDownloader{probeErr: errors.New("exit status 1")}, wantErr: ErrInvalidURL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &VideoService{conf: conf, dl: tt.dl}
+
+			videoInfo, playlist, err := s.getVideoInfo(context.Background(), "https://youtu.be/dQw4w9WgXcQ")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if len(tt.wantVideo) > 0 {
+				if playlist != nil || videoInfo == nil || videoInfo.ID != tt.wantVideo {
+					t.Fatalf("got video %+v, playlist %+v", videoInfo, playlist)
+				}
+				if !videoInfo.Youtube || videoInfo.Duration != 212 || len(videoInfo.Formats) != 2 {
+					t.Errorf("video parsed wrong: %+v", videoInfo)
+				}
+				return
+			}
+
+			if videoInfo != nil || playlist == nil {
+				t.Fatalf("got video %+v, playlist %+v", videoInfo, playlist)
+			}
+			if len(playlist.Entries) != tt.wantEntries || playlist.Total != tt.wantTotal {
+				t.Errorf("got %d of %d entries, want %d of %d", len(playlist.Entries), playlist.Total, tt.wantEntries, tt.wantTotal)
+			}
+		})
+	}
+}
+
+var errFetch = errors.New("fetch failed")
+
+func TestDownloadVideo(t *testing.T) {
+	tests := []struct {
+		name    string
+		dl      *fakeDownloader
+		opt     *models.VideoOption
+		wantErr error
+	}{
+		{
+			name: "downloaded",
+			dl:   &fakeDownloader{fetchFiles: []string{""}},
+			opt:  &models.VideoOption{FormatID: "18"},
+		},
+		{
+			name:    "partial streams removed",
+			dl:      &fakeDownloader{fetchFiles: []string{".f137.mp4.part", ".f140.m4a"}, fetchErr: errFetch},
+			opt:     &models.VideoOption{FormatID: "137+140"},
+			wantErr: errFetch,
+		},
+		{
+			name: "missing subtitles",
+			dl:   &fakeDownloader{fetchFiles: []string{""}},
+			opt: &models.VideoOption{
+				FormatID:     "18",
+				Subtitle:     &models.SubtitleTrack{Lang: "en"},
+				SubtitleMode: models.SubtitleModeBurn.Name,
+			},
+			wantErr: ErrSubtitlesNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &VideoService{dl: tt.dl}
+
+			filePath, err := s.DownloadVideo(context.Background(), tt.opt, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if len(tt.dl.fetched) != 1 {
+				t.Fatalf("fetched %d times", len(tt.dl.fetched))
+			}
+
+			baseName := strings.TrimSuffix(tt.dl.fetched[0], filepath.Ext(tt.dl.fetched[0]))
+			left, _ := filepath.Glob(baseName + "*")
+			defer removeFiles(left)
+
+			if err == nil {
+				if filePath != tt.dl.fetched[0] || len(left) != 1 {
+					t.Errorf("got %s, files %v", filePath, left)
+				}
+				return
+			}
+
+			if len(left) > 0 {
+				t.Errorf("files left after error: %v", left)
+			}
+		})
+	}
+}
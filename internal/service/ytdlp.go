@@ -0,0 +1,386 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/far4599/telegram-bot-youtube-download/internal/models"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/log"
+	"github.com/far4599/telegram-bot-youtube-download/internal/pkg/metrics"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	defaultYtDlpBinary = "yt-dlp"
+	cacheDir           = "/tmp/yt-dlp"
+
+	progressPrefix   = "[progress]"
+	progressTemplate = "download:" + progressPrefix + "%(progress.downloaded_bytes)s %(progress.total_bytes,progress.total_bytes_estimate)s %(progress.speed)s %(progress.eta)s"
+)
+
+type YtDlpDownloader struct {
+	binary   string
+	maxRetry uint
+}
+
+func NewYtDlpDownloader(binary string, maxRetry uint) *YtDlpDownloader {
+	if len(binary) == 0 {
+		binary = defaultYtDlpBinary
+	}
+
+	return &YtDlpDownloader{
+		binary:   binary,
+		maxRetry: maxRetry,
+	}
+}
+
+func (d *YtDlpDownloader) Probe(ctx context.Context, url string) ([]byte, error) {
+	return readAll(d.runWithRetry(ctx, url, true, nil, "--no-download"))
+}
+
+func (d *YtDlpDownloader) Version(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, d.binary, "--version").Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to run '%s'", d.binary)
+	}
+
+	version := strings.TrimSpace(string(out))
+	if len(version) == 0 {
+		return "", errors.Errorf("'%s' printed no version", d.binary)
+	}
+
+	return version, nil
+}
+
+func (d *YtDlpDownloader) Fetch(ctx context.Context, format *models.VideoOption, path string, onProgress ProgressFunc) error {
+	args := []string{
+		"-o", path,
+		"-f", format.FormatID,
+		// separate video and audio streams are merged with ffmpeg
+		"--merge-output-format", "mp4",
+		"--no-playlist",
+	}
+
+	if format.Audio {
+		codec := models.AudioCodecByName(format.AudioCodec)
+		args = append(args,
+			"--extract-audio",
+			"--audio-format", codec.Name,
+			"--audio-quality", codec.Quality,
+			// artist, track, album and uploader tags
+			"--embed-metadata",
+			"--embed-thumbnail",
+			"--convert-thumbnails", "jpg",
+		)
+	}
+
+	if format.Subtitle != nil {
+		args = append(args, subtitleArgs(format)...)
+	}
+
+	if format.Clip != nil {
+		args = append(args,
+			"--download-sections", fmt.Sprintf("*%d-%d", format.Clip.Start, format.Clip.End),
+			// cut precisely instead of the nearest keyframes
+			"--force-keyframes-at-cuts",
+		)
+	}
+
+	if onProgress != nil {
+		args = append(args, "--progress", "--newline", "--progress-template", progressTemplate)
+	} else {
+		args = append(args, "--no-progress")
+	}
+
+	resp, err := d.runWithRetry(ctx, format.VideoInfo.URL, false, onProgress, args...)
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+
+	errGroup, errCtx := errgroup.WithContext(ctx)
+
+	errGroup.Go(func() error {
+		select {
+		case <-errCtx.Done():
+			return nil
+		case <-resp.closeCh:
+			return nil
+		case dlpErr, ok := <-resp.errCh:
+			if !ok {
+				return nil
+			}
+			return dlpErr
+		}
+	})
+
+	errGroup.Go(func() error {
+		// depending on the version yt-dlp prints progress either to stdout or to stderr
+		stdoutLineScanner := bufio.NewScanner(resp.out)
+		for stdoutLineScanner.Scan() {
+			line := stdoutLineScanner.Text()
+			if p, ok := parseProgress(line); ok {
+				if onProgress != nil {
+					onProgress(p)
+				}
+			} else {
+				log.Logger.Debug(line)
+			}
+		}
+
+		if errG := stdoutLineScanner.Err(); errG != nil {
+			return errG
+		}
+
+		// wait for yt-dlp to finish merging the file
+		return resp.Wait()
+	})
+
+	err = errGroup.Wait()
+
+	// yt-dlp killed on cancel may exit without error
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	return err
+}
+
+func subtitleArgs(format *models.VideoOption) []string {
+	writeSubs := "--write-subs"
+	if format.Subtitle.Auto {
+		writeSubs = "--write-auto-subs"
+	}
+
+	args := []string{writeSubs, "--sub-langs", format.Subtitle.Lang}
+
+	mode := models.SubtitleModeByName(format.SubtitleMode)
+	switch {
+	case mode.File():
+		args = append(args, "--skip-download", "--convert-subs", mode.Ext)
+	case mode == models.SubtitleModeSoft:
+		args = append(args, "--embed-subs")
+	default:
+		// burned by ffmpeg after download
+		args = append(args, "--convert-subs", models.SubtitleModeSRT.Ext)
+	}
+
+	return args
+}
+
+func (d *YtDlpDownloader) runWithRetry(ctx context.Context, url string, isJson bool, onProgress ProgressFunc, args ...string) (result *dlpResponse, err error) {
+	err = retry.Do(
+		func() error {
+			res, errR := d.run(ctx, url, isJson, onProgress, args...)
+			if errR != nil {
+				return errR
+			}
+
+			result = res
+
+			return nil
+		},
+		retry.Context(ctx),
+		retry.Attempts(d.maxRetry),
+	)
+
+	return
+}
+
+func (d *YtDlpDownloader) run(ctx context.Context, url string, isJson bool, onProgress ProgressFunc, args ...string) (*dlpResponse, error) {
+	defaultArgs := []string{
+		"-q", "-v",
+		"--ignore-errors",
+		"--no-call-home",
+		"--geo-bypass",
+		"--cache-dir", cacheDir,
+		// provide URL via stdin for security, youtube-dl has some run command args
+		"--batch-file", "-",
+	}
+
+	if isJson {
+		// playlist entries are not resolved, so a playlist is returned as a single json with entries
+		defaultArgs = append(defaultArgs, "-J", "--flat-playlist")
+	}
+
+	args = append(defaultArgs, args...)
+
+	log.Logger.Infow("yt-dlp arguments", "binary", d.binary, "args", args, "url", url)
+
+	cmd := exec.CommandContext(
+		ctx,
+		d.binary,
+		args...,
+	)
+
+	cmd.Stdin = bytes.NewBufferString(url + "\n")
+
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	errOut, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	errCh := make(chan error, 10)
+
+	go func() {
+		const errorPrefix = "ERROR: "
+		stderrLineScanner := bufio.NewScanner(errOut)
+		for stderrLineScanner.Scan() {
+			line := stderrLineScanner.Text()
+			if strings.HasPrefix(line, errorPrefix) {
+				log.Logger.Errorw("yt-dlp returned error", "error", line)
+				metrics.YtDlpErrors.WithLabelValues(dlpError(line).Category()).Inc()
+				errCh <- dlpError(line)
+			} else if p, ok := parseProgress(line); ok {
+				if onProgress != nil {
+					onProgress(p)
+				}
+			} else {
+				log.Logger.Debug(line)
+			}
+		}
+	}()
+
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	resp := &dlpResponse{
+		out:     out,
+		errCh:   errCh,
+		closeCh: make(chan struct{}),
+		doneCh:  make(chan struct{}),
+	}
+
+	go func() {
+		<-resp.closeCh
+
+		resp.exitErr = cmd.Wait()
+		if cmd.ProcessState != nil {
+			metrics.YtDlpExits.WithLabelValues(strconv.Itoa(cmd.ProcessState.ExitCode())).Inc()
+		}
+		close(resp.doneCh)
+	}()
+
+	return resp, nil
+}
+
+// parseProgress parses a line printed by yt-dlp with progressTemplate.
+func parseProgress(line string) (models.Progress, bool) {
+	if !strings.HasPrefix(line, progressPrefix) {
+		return models.Progress{}, false
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(line, progressPrefix))
+	if len(fields) != 4 {
+		return models.Progress{}, false
+	}
+
+	values := make([]float64, len(fields))
+	for i, field := range fields {
+		// yt-dlp prints NA for unknown values
+		values[i], _ = strconv.ParseFloat(field, 64)
+	}
+
+	return models.Progress{
+		Downloaded: uint64(values[0]),
+		Total:      uint64(values[1]),
+		Speed:      values[2],
+		ETA:        time.Duration(values[3]) * time.Second,
+	}, true
+}
+
+func readAll(resp *dlpResponse, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+
+	return io.ReadAll(resp.out)
+}
+
+type dlpResponse struct {
+	out     io.ReadCloser
+	errCh   chan error
+	closeCh chan struct{}
+	doneCh  chan struct{}
+	exitErr error
+
+	closeMu sync.Mutex
+	closed  bool
+}
+
+func (r *dlpResponse) Close() {
+	r.closeMu.Lock()
+	defer r.closeMu.Unlock()
+
+	if r.closed {
+		return
+	}
+
+	defer close(r.closeCh)
+	defer r.out.Close()
+
+	r.closed = true
+}
+
+// Wait closes the response and waits for yt-dlp to exit.
+func (r *dlpResponse) Wait() error {
+	r.Close()
+	<-r.doneCh
+
+	if r.exitErr != nil {
+		return errors.Wrap(r.exitErr, "yt-dlp exited with error")
+	}
+
+	return nil
+}
+
+type dlpError string
+
+func (e dlpError) Error() string {
+	return string(e)
+}
+
+// dlpErrorCategories are matched against the error message in order, the first match wins
+var dlpErrorCategories = []struct {
+	category string
+	patterns []string
+}{
+	{"unsupported", []string{"Unsupported URL", "is not a valid URL"}},
+	{"format", []string{"Requested format is not available"}},
+	{"private", []string{"Private video", "is private"}},
+	{"auth", []string{"Sign in", "login", "age-restricted", "age restricted"}},
+	{"geo", []string{"geo restriction", "in your country"}},
+	{"rate_limited", []string{"HTTP Error 429", "Too Many Requests"}},
+	{"unavailable", []string{"unavailable", "not available", "removed", "HTTP Error 404"}},
+	{"network", []string{"HTTP Error", "timed out", "Connection", "Unable to download"}},
+}
+
+// Category returns a rough reason of the error for metrics.
+func (e dlpError) Category() string {
+	for _, c := range dlpErrorCategories {
+		for _, pattern := range c.patterns {
+			if strings.Contains(string(e), pattern) {
+				return c.category
+			}
+		}
+	}
+
+	return "other"
+}